@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/apikey"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const apiKeyUsage = `usage: helpdesk apikey <command> [flags]
+
+commands:
+  mint    mint a key and print its token
+  list    list the keys
+  revoke  revoke a key by ID
+
+The keys are stored in the file named by -file or APIKEYS_FILE.`
+
+// runAPIKey manages API keys from the command line, e.g. to mint the first
+// key before any admin can sign in.
+func runAPIKey(args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	file := fs.String("file", os.Getenv("APIKEYS_FILE"), "API keys file")
+
+	switch args[0] {
+	case "mint":
+		name := fs.String("name", "", "name of the key, e.g. bi-job")
+		scopes := fs.String("scopes", "", "comma separated scopes, e.g. tickets:read,reports:read")
+		ttl := fs.Duration("ttl", 0, "validity of the key, e.g. 8760h; 0 never expires")
+		rateLimit := fs.Float64("rate", 0, "requests per second to each route group; 0 keeps the group limits")
+		burst := fs.Int("burst", 0, "requests at once; defaults to the rate")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		store, err := openAPIKeys(*file)
+		if err != nil {
+			return err
+		}
+		key, token, err := store.Mint(&apikey.MintRequest{
+			Name:      *name,
+			Scopes:    strings.Split(*scopes, ","),
+			RateLimit: *rateLimit,
+			Burst:     *burst,
+			TTL:       config.Duration(*ttl),
+		})
+		if err != nil {
+			return describeErr(err)
+		}
+
+		fmt.Fprintf(os.Stderr, "minted key %s, store the token now, it cannot be shown again:\n", key.ID)
+		fmt.Println(token)
+		return nil
+
+	case "list":
+		asJSON := fs.Bool("json", false, "print JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		store, err := openAPIKeys(*file)
+		if err != nil {
+			return err
+		}
+		keys, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(keys)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSCOPES\tEXPIRES\tSTATE")
+		now := time.Now()
+		for _, k := range keys {
+			expires, state := "never", "active"
+			if k.ExpiresAt != nil {
+				expires = k.ExpiresAt.Format(time.RFC3339)
+			}
+			switch {
+			case k.RevokedAt != nil:
+				state = "revoked"
+			case !k.Active(now):
+				state = "expired"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, strings.Join(k.Scopes, ","), expires, state)
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("usage: helpdesk apikey revoke [-file path] <id>")
+		}
+
+		store, err := openAPIKeys(*file)
+		if err != nil {
+			return err
+		}
+		key, err := store.Revoke(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("revoked key %s (%s)\n", key.ID, key.Name)
+		return nil
+
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
+
+// describeErr appends the field violations of a status error to its message.
+func describeErr(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	msg := s.Message()
+	for _, d := range s.Details() {
+		if br, ok := d.(*edpb.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				msg += " " + v.GetField() + ": " + v.GetDescription()
+			}
+		}
+	}
+	return errors.New(msg)
+}
+
+func openAPIKeys(path string) (*apikey.Store, error) {
+	if path == "" {
+		return nil, errors.New("set -file or APIKEYS_FILE")
+	}
+	return apikey.Open(path)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+)
+
+const configUsage = `usage: helpdesk config check [flags]
+
+Loads the configuration as the server would, from -config or CONFIG_FILE,
+the environment and the flags, and prints it with its secrets redacted.`
+
+// runConfig validates the configuration without starting the server.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return errors.New(configUsage)
+	}
+
+	cfg, err := config.Load(os.Args[0]+" config check", args[1:])
+	if err != nil {
+		return err
+	}
+
+	redacted := cfg.Redacted()
+	b, err := json.MarshalIndent(redacted, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+
+	fmt.Fprintln(os.Stderr, "db dsn:", redacted.DB.DSN())
+	fmt.Fprintln(os.Stderr, "config is valid")
+	return nil
+}
@@ -3,86 +3,308 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	hspb "github.com/10664kls/helpdesk-dashboad-api/genproto/go/http/v1"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/apikey"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/audit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/cors"
 	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/httperr"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/logging"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/metrics"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/ratelimit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/schedule"
 	"github.com/10664kls/helpdesk-dashboad-api/internal/server"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/tracing"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/webhook"
 
 	"github.com/labstack/echo/v4"
 	stdmw "github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+	"golang.org/x/text/language"
 
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/genproto/googleapis/rpc/code"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+
 	_ "github.com/denisenkom/go-mssqldb"
 )
 
 func main() {
-	if err := run(); err != nil {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "apikey":
+			if err := runAPIKey(os.Args[2:]); err != nil {
+				log.Fatalf("apikey: %v", err)
+			}
+			return
+
+		case "config":
+			if err := runConfig(os.Args[2:]); err != nil {
+				log.Fatalf("config: %v", err)
+			}
+			return
+		}
+	}
+
+	if err := run(os.Args[1:]); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
 }
 
-func run() error {
+func run(args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	zlog, err := newLogger()
+	cfg, err := config.Load(os.Args[0], args)
+	if err != nil {
+		return err
+	}
+
+	zlog, logLevel, err := logging.New(&cfg.Log)
 	if err != nil {
 		return err
 	}
 	defer zlog.Sync()
 	zap.ReplaceGlobals(zlog)
 
-	db, err := sql.Open(
-		"sqlserver",
-		fmt.Sprintf("sqlserver://%s:%s@%s:%s?database=%s&TrustServerCertificate=true",
-			os.Getenv("DB_USER"),
-			os.Getenv("DB_PASSWORD"),
-			os.Getenv("DB_HOST"),
-			os.Getenv("DB_PORT"),
-			os.Getenv("DB_NAME"),
-		),
-	)
+	shutdownTracing, err := tracing.Setup(ctx, &cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Flush the spans of the last requests.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			zlog.Error("failed to shut down tracing", zap.Error(err))
+		}
+	}()
+
+	db, err := sql.Open("sqlserver", cfg.DB.DSN())
 	if err != nil {
 		return fmt.Errorf("failed to create db connection: %w", err)
 	}
 	defer db.Close()
 
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.DB.ConnMaxLifetime))
+	db.SetConnMaxIdleTime(time.Duration(cfg.DB.ConnMaxIdleTime))
+
 	if err := db.PingContext(ctx); err != nil {
 		return fmt.Errorf("failed to ping DB: %w", err)
 	}
 
 	e := echo.New()
 	e.HideBanner = true
-	e.Use(stdmws()...)
+	e.Server.ReadHeaderTimeout = time.Duration(cfg.HTTP.ReadHeaderTimeout)
+	e.Server.IdleTimeout = time.Duration(cfg.HTTP.IdleTimeout)
+
+	// Rate limits and the audit log key on the client IP, so it must not
+	// be taken from headers a client can set.
+	e.IPExtractor, err = cfg.HTTP.IPExtractor()
+	if err != nil {
+		return err
+	}
+
+	corsCfg, err := cfg.CORS.Resolve()
+	if err != nil {
+		return err
+	}
+
+	// The request span encloses the other middlewares, so their time and
+	// the panics recovered count in it.
+	e.Use(tracing.Middleware())
+	e.Use(stdmws(corsCfg, zlog)...)
 	e.HTTPErrorHandler = httpErr
 
-	hSvc, err := helpdesk.NewService(ctx, db, zlog)
+	if cfg.Metrics.Enabled {
+		if err := metrics.RegisterDB(db, cfg.DB.Name); err != nil {
+			return fmt.Errorf("failed to register db metrics: %w", err)
+		}
+		e.Use(metrics.Middleware())
+
+		// Metrics are served apart from the API, which is public.
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsSrv := &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		}
+		ln, err := net.Listen("tcp", cfg.Metrics.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for metrics: %w", err)
+		}
+		go func() {
+			if err := metricsSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				zlog.Error("failed to serve metrics", zap.Error(err))
+			}
+		}()
+		defer metricsSrv.Close()
+	}
+
+	limits := ratelimit.New(cfg.RateLimits)
+	e.Use(limits.ByIP())
+
+	loc, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return fmt.Errorf("failed to load time zone: %w", err)
+	}
+
+	opts := []helpdesk.Option{
+		helpdesk.WithLocation(loc),
+		helpdesk.WithReportCache(time.Duration(cfg.ReportCache.TTL)),
+	}
+	if path := cfg.Export.TemplatesFile; path != "" {
+		templates, err := helpdesk.LoadExportTemplates(path)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, helpdesk.WithExportTemplates(templates))
+	}
+	if path := cfg.Export.PDFFontFile; path != "" {
+		ttf, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read pdf font: %w", err)
+		}
+		opts = append(opts, helpdesk.WithPDFFont(ttf))
+	}
+
+	hSvc, err := helpdesk.NewService(ctx, db, zlog, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create helpdesk service: %w", err)
 	}
 
-	server := must(server.NewServer(hSvc))
-	if err := server.Install(e); err != nil {
+	// The watcher polls only while someone subscribes, so webhooks and
+	// ticket streams share a single loop.
+	watcher, err := hSvc.NewWatcher(time.Duration(cfg.Watch.Interval), time.Duration(cfg.Watch.Lookback))
+	if err != nil {
+		return fmt.Errorf("failed to create ticket watcher: %w", err)
+	}
+	go watcher.Run(ctx)
+
+	if cfg.Metrics.Enabled {
+		go hSvc.RecordOpenTickets(ctx, time.Duration(cfg.Metrics.OpenTicketsInterval))
+	}
+
+	serverOpts := []server.Option{
+		server.WithWatcher(watcher),
+		server.WithRateLimits(limits),
+		server.WithLogLevel(logLevel),
+		server.WithReadiness(server.Readiness{
+			Timeout:   time.Duration(cfg.Health.Timeout),
+			CheckView: cfg.Health.CheckView,
+		}),
+	}
+	if path := cfg.CacheControlFile; path != "" {
+		routes, err := server.LoadCacheControl(path)
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithCacheControl(routes))
+	}
+
+	// The verifiers stay nil interfaces unless configured.
+	var tokens, keys auth.Verifier
+	if path := cfg.AuthFile; path != "" {
+		authCfg, err := auth.LoadConfig(path)
+		if err != nil {
+			return err
+		}
+
+		authn, err := auth.NewAuthenticator(ctx, authCfg, zlog)
+		if err != nil {
+			return fmt.Errorf("failed to create authenticator: %w", err)
+		}
+		tokens = authn
+	}
+	if path := cfg.APIKeysFile; path != "" {
+		store, err := apikey.Open(path)
+		if err != nil {
+			return err
+		}
+		keys = store
+		serverOpts = append(serverOpts, server.WithAPIKeys(store))
+	}
+
+	if path := cfg.AuditLogFile; path != "" {
+		auditLog, err := audit.Open(path)
+		if err != nil {
+			return err
+		}
+		defer auditLog.Close()
+		serverOpts = append(serverOpts, server.WithAuditLog(auditLog))
+	}
+
+	// Config.Validate refuses to run without verifiers unless
+	// authentication is disabled explicitly.
+	var mdw []echo.MiddlewareFunc
+	switch {
+	case tokens != nil || keys != nil:
+		mdw = append(mdw, auth.Middleware(tokens, keys))
+	case cfg.Auth.Disabled:
+		zlog.Warn("authentication is disabled by auth.disabled")
+	default:
+		return errors.New("authentication is not configured")
+	}
+
+	server := must(server.NewServer(hSvc, serverOpts...))
+	if err := server.Install(e, mdw...); err != nil {
 		return fmt.Errorf("failed to install server: %w", err)
 	}
 
+	if path := cfg.SchedulesFile; path != "" {
+		scheduleCfg, err := schedule.LoadConfig(path)
+		if err != nil {
+			return err
+		}
+
+		scheduler, err := schedule.NewScheduler(scheduleCfg, hSvc, schedule.NewSMTPSender(scheduleCfg.SMTP), zlog)
+		if err != nil {
+			return fmt.Errorf("failed to create scheduler: %w", err)
+		}
+		scheduler.Start()
+		defer func() {
+			<-scheduler.Stop().Done()
+		}()
+	}
+
+	if path := cfg.WebhooksFile; path != "" {
+		webhookCfg, err := webhook.LoadConfig(path)
+		if err != nil {
+			return err
+		}
+
+		dispatcher, err := webhook.NewDispatcher(webhookCfg, zlog)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook dispatcher: %w", err)
+		}
+
+		events, unsubscribe := watcher.Subscribe(256)
+		defer unsubscribe()
+		go dispatcher.Run(ctx, events)
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- e.Start(fmt.Sprintf(":%s", getEnv("PORT", "8089")))
+		errCh <- e.Start(cfg.HTTP.Addr())
 	}()
 
 	ctx, cancel = signal.NotifyContext(ctx, os.Interrupt, os.Kill, syscall.SIGTERM)
@@ -92,7 +314,8 @@ func run() error {
 	case <-ctx.Done():
 		zlog.Info("shutting down server")
 
-		ctx, cancel := context.WithTimeout(ctx, time.Second*15)
+		// ctx is done already, so the grace period must not derive from it.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HTTP.ShutdownTimeout))
 		defer cancel()
 		if err := e.Shutdown(ctx); err != nil {
 			zlog.Error("failed to shutdown server", zap.Error(err))
@@ -110,109 +333,69 @@ func run() error {
 	return nil
 }
 
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	return fallback
-}
+// httpErr writes err as an hspb.Error. Errors that carry no status are
+// written as Internal without their message, which may reveal queries or
+// paths, and logged with the request ID.
+func httpErr(err error, c echo.Context) {
+	ctx := c.Request().Context()
+	lang := i18n.Negotiate(c.QueryParam("lang"), c.Request().Header.Get("Accept-Language"))
 
-func newLogger() (*zap.Logger, error) {
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "timestamp",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "message",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.StringDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-
-	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zap.DebugLevel),
-		Development:      false,
-		Encoding:         "console",
-		EncoderConfig:    encoderConfig,
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
-	}
-
-	zlog, err := config.Build()
-	if err != nil {
-		return nil, fmt.Errorf("failed to build zap log: %w", err)
+	s := httperr.Status(err)
+	if s.Code() == codes.Internal || s.Code() == codes.Unknown {
+		logging.With(ctx, zap.L()).Error("failed to serve request", zap.Error(err))
 	}
-
-	return zlog, nil
-}
-
-func httpErr(err error, c echo.Context) {
-	if s, ok := status.FromError(err); ok {
-		he := httpStatusPbFromRPC(s)
-		jsonb, _ := protojson.Marshal(he)
-		c.JSONBlob(int(he.Error.Code), jsonb)
+	if c.Response().Committed {
 		return
 	}
 
-	if he, ok := err.(*echo.HTTPError); ok {
-		var s *status.Status
-		switch he.Code {
-		case http.StatusNotFound:
-			s = status.New(codes.NotFound, "Not found!")
-
-		case http.StatusTooManyRequests:
-			s = status.New(codes.ResourceExhausted, "Too many requests.")
+	httpCode := httperr.HTTPCode(err)
 
-		default:
-			s = status.New(codes.Unknown, "Unknown error!")
+	if id := logging.RequestIDFromContext(ctx); id != "" {
+		if rs, err := s.WithDetails(&edpb.RequestInfo{RequestId: id}); err == nil {
+			s = rs
 		}
+	}
 
-		hbp := httpStatusPbFromRPC(s)
-		jsonb, _ := protojson.Marshal(hbp)
-		c.JSONBlob(int(hbp.Error.Code), jsonb)
-		return
+	hbp := httpStatusPbFromRPC(localizeStatus(s, lang), httpCode)
+	jsonb, _ := protojson.Marshal(hbp)
+	c.JSONBlob(httpCode, jsonb)
+}
+
+// localizeStatus translates the message of s to lang. The translation is
+// also attached as a LocalizedMessage detail.
+func localizeStatus(s *status.Status, lang language.Tag) *status.Status {
+	msg := i18n.T(lang, s.Message())
+	if msg == s.Message() {
+		return s
 	}
 
-	c.JSON(http.StatusInternalServerError, echo.Map{
-		"code":    500,
-		"status":  "INTERNAL_ERROR",
-		"message": "An internal error occurred",
+	p := s.Proto()
+	p.Message = msg
+	ls, err := status.FromProto(p).WithDetails(&edpb.LocalizedMessage{
+		Locale:  lang.String(),
+		Message: msg,
 	})
+	if err != nil {
+		return s
+	}
+	return ls
 }
 
-func stdmws() []echo.MiddlewareFunc {
+func stdmws(corsCfg *cors.Config, zlog *zap.Logger) []echo.MiddlewareFunc {
 	return []echo.MiddlewareFunc{
 		stdmw.RemoveTrailingSlash(),
-		// stdmw.Logger(),
+		logging.RequestID(),
+		logging.AccessLog(zlog),
 		stdmw.Recover(),
-		stdmw.CORSWithConfig(stdmw.CORSConfig{
-			AllowOriginFunc: func(origin string) (bool, error) {
-				return true, nil
-			},
-			AllowMethods: []string{
-				http.MethodHead,
-				http.MethodGet,
-				http.MethodPost,
-				http.MethodPut,
-				http.MethodPatch,
-				http.MethodDelete,
-				http.MethodOptions,
-			},
-			AllowCredentials: true,
-			MaxAge:           86400,
-		}),
-		stdmw.RateLimiter(stdmw.NewRateLimiterMemoryStore(10)),
+		corsCfg.Middleware(),
 		stdmw.Secure(),
 	}
 }
 
-func httpStatusPbFromRPC(s *status.Status) *hspb.Error {
+func httpStatusPbFromRPC(s *status.Status, httpCode int) *hspb.Error {
 	return &hspb.Error{
 		Error: &hspb.Status{
-			Code:    int32(runtime.HTTPStatusFromCode(s.Code())),
+			Code:    int32(httpCode),
 			Message: s.Message(),
 			Status:  code.Code(s.Code()),
 			Details: s.Proto().GetDetails(),
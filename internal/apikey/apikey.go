@@ -0,0 +1,139 @@
+// Package apikey manages the API keys services authenticate with.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+)
+
+// Key is an API key as it is stored. The secret itself is never stored,
+// only its SHA-256 hash.
+type Key struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes"`
+
+	// RateLimit is the number of requests per second the key may make to
+	// each route group, and Burst the number it may make at once. They
+	// override the limits of the groups. Zero means the group limits apply.
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// MintRequest describes a key to mint.
+type MintRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	RateLimit float64  `json:"rateLimit"`
+	Burst     int      `json:"burst"`
+
+	// TTL is how long the key is valid for. Zero means it does not expire.
+	TTL config.Duration `json:"ttl"`
+}
+
+// validate checks the request.
+func (r *MintRequest) validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is empty")
+	}
+	if len(r.Scopes) == 0 {
+		return errors.New("no scopes")
+	}
+	for _, s := range r.Scopes {
+		if !slices.Contains(auth.Scopes, s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	if r.RateLimit < 0 || r.Burst < 0 {
+		return errors.New("rate limit must not be negative")
+	}
+	if r.TTL < 0 {
+		return errors.New("ttl must not be negative")
+	}
+	return nil
+}
+
+// newKey returns a key for the request and the secret to hand out,
+// of the form hdk_<id>_<secret>.
+func newKey(r *MintRequest, now time.Time) (*Key, string, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+
+	k := &Key{
+		ID:        hex.EncodeToString(id),
+		Name:      r.Name,
+		Scopes:    r.Scopes,
+		RateLimit: r.RateLimit,
+		Burst:     r.Burst,
+		CreatedAt: now.UTC(),
+	}
+	if r.TTL > 0 {
+		exp := k.CreatedAt.Add(time.Duration(r.TTL))
+		k.ExpiresAt = &exp
+	}
+	k.defaultBurst()
+
+	token := auth.KeyPrefix + k.ID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	k.Hash = hashToken(token)
+
+	return k, token, nil
+}
+
+// hashToken returns the hex SHA-256 of a token. Tokens hold 256 random bits,
+// so a fast hash is enough to keep them safe at rest.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseToken returns the key ID of a token.
+func parseToken(token string) (string, bool) {
+	rest, ok := strings.CutPrefix(token, auth.KeyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	return id, ok && id != ""
+}
+
+// defaultBurst sets the burst of a rate limited key without one, which
+// would otherwise refuse every request.
+func (k *Key) defaultBurst() {
+	if k.RateLimit > 0 && k.Burst == 0 {
+		k.Burst = max(1, int(k.RateLimit))
+	}
+}
+
+// Limit returns the rate limit of the key, or nil if it has none.
+func (k *Key) Limit() *config.Limit {
+	if k.RateLimit <= 0 {
+		return nil
+	}
+	return &config.Limit{Rate: k.RateLimit, Burst: k.Burst}
+}
+
+// Active reports whether the key can be used at t.
+func (k *Key) Active(t time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || t.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,251 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// ErrKeyNotFound is returned when revoking a key that does not exist.
+var ErrKeyNotFound = errors.New("api key not found")
+
+// reloadInterval is how often the store checks whether the file changed,
+// e.g. because a key was minted from the command line.
+const reloadInterval = 5 * time.Second
+
+// Store keeps API keys in a JSON file.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	keys    map[string]*Key
+	modTime time.Time
+	checked time.Time
+}
+
+// Open returns the store of the file at path. The file is created when the
+// first key is minted.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		keys: make(map[string]*Key),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// load reads the file if it changed since it was last read.
+func (s *Store) load() error {
+	s.checked = time.Now()
+
+	fi, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat api keys: %w", err)
+	}
+	if fi.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read api keys: %w", err)
+	}
+
+	var file struct {
+		Keys []*Key `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return fmt.Errorf("failed to parse api keys: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(file.Keys))
+	for _, k := range file.Keys {
+		if k.RateLimit < 0 || k.Burst < 0 {
+			return fmt.Errorf("api key %s: rate limit must not be negative", k.ID)
+		}
+		// The file may be edited by hand.
+		k.defaultBurst()
+		keys[k.ID] = k
+	}
+	s.keys, s.modTime = keys, fi.ModTime()
+
+	return nil
+}
+
+// save writes the keys to the file, replacing it atomically.
+func (s *Store) save() error {
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	b, err := json.MarshalIndent(struct {
+		Keys []*Key `json:"keys"`
+	}{keys}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write api keys: %w", err)
+	}
+
+	if fi, err := os.Stat(s.path); err == nil {
+		s.modTime = fi.ModTime()
+	}
+	return nil
+}
+
+// Mint creates a key and returns it with its token. The token is not
+// stored and cannot be recovered.
+func (s *Store) Mint(r *MintRequest) (*Key, string, error) {
+	if err := r.validate(); err != nil {
+		st, _ := status.New(codes.InvalidArgument, "API key is invalid.").
+			WithDetails(&edpb.BadRequest{
+				FieldViolations: []*edpb.BadRequest_FieldViolation{
+					{
+						Field:       "key",
+						Description: err.Error(),
+					},
+				},
+			})
+		return nil, "", st.Err()
+	}
+
+	k, token, err := newKey(r, time.Now())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, "", err
+	}
+	s.keys[k.ID] = k
+	if err := s.save(); err != nil {
+		delete(s.keys, k.ID)
+		return nil, "", err
+	}
+
+	return k, token, nil
+}
+
+// Revoke revokes the key with the given ID.
+func (s *Store) Revoke(id string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if k.RevokedAt == nil {
+		now := time.Now().UTC()
+		k.RevokedAt = &now
+		if err := s.save(); err != nil {
+			k.RevokedAt = nil
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// List returns every key, oldest first.
+func (s *Store) List() ([]*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		c := *k
+		keys = append(keys, &c)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	return keys, nil
+}
+
+// Verify implements auth.Verifier. It checks the token and the expiry of
+// the key. The rate limit of the key is returned in the identity, to be
+// enforced by package ratelimit.
+func (s *Store) Verify(_ context.Context, token string) (*auth.Identity, error) {
+	id, ok := parseToken(token)
+	if !ok {
+		return nil, auth.Unauthenticated("INVALID_API_KEY", "API key is invalid.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checked) >= reloadInterval {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	k, ok := s.keys[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashToken(token))) != 1 {
+		return nil, auth.Unauthenticated("INVALID_API_KEY", "API key is invalid.")
+	}
+	if !k.Active(time.Now()) {
+		return nil, auth.Unauthenticated("API_KEY_EXPIRED", "API key has expired or was revoked.")
+	}
+
+	return &auth.Identity{
+		UserID: "apikey:" + k.Name,
+		KeyID:  k.ID,
+		Scopes: k.Scopes,
+		Limit:  k.Limit(),
+	}, nil
+}
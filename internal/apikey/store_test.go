@@ -0,0 +1,181 @@
+package apikey
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStoreVerify(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, token, err := s.Mint(&MintRequest{
+		Name:      "bi",
+		Scopes:    []string{auth.ScopeReportsRead},
+		RateLimit: 2,
+	})
+	if err != nil {
+		t.Fatalf("Mint() = %v", err)
+	}
+	if k.Burst != 2 {
+		t.Errorf("Burst = %d, want the default of 2", k.Burst)
+	}
+
+	id, err := s.Verify(t.Context(), token)
+	if err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if id.KeyID != k.ID || id.UserID != "apikey:bi" {
+		t.Errorf("identity = %+v, want key %s of apikey:bi", id, k.ID)
+	}
+	if id.Limit == nil || *id.Limit != (config.Limit{Rate: 2, Burst: 2}) {
+		t.Errorf("Limit = %+v, want rate 2 and burst 2", id.Limit)
+	}
+
+	// Verify does not limit the rate itself.
+	for range 5 {
+		if _, err := s.Verify(t.Context(), token); err != nil {
+			t.Fatalf("Verify() = %v", err)
+		}
+	}
+
+	for _, bad := range []string{
+		"hdk_",
+		"hdk_" + k.ID,
+		token + "x",
+		strings.Replace(token, k.ID, "0000000000000000", 1),
+	} {
+		if _, err := s.Verify(t.Context(), bad); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Verify(%q) = %v, want Unauthenticated", bad, err)
+		}
+	}
+
+	if _, err := s.Revoke(k.ID); err != nil {
+		t.Fatalf("Revoke() = %v", err)
+	}
+	if _, err := s.Verify(t.Context(), token); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Verify() of a revoked key = %v, want Unauthenticated", err)
+	}
+	if _, err := s.Revoke("missing"); err != ErrKeyNotFound {
+		t.Errorf("Revoke() = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestStoreNoLimit(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, token, err := s.Mint(&MintRequest{Name: "etl", Scopes: []string{auth.ScopeTicketsRead}})
+	if err != nil {
+		t.Fatalf("Mint() = %v", err)
+	}
+	id, err := s.Verify(t.Context(), token)
+	if err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if id.Limit != nil {
+		t.Errorf("Limit = %+v, want nil", id.Limit)
+	}
+}
+
+func TestStoreLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		wantErr   bool
+		wantLimit *config.Limit
+	}{
+		{
+			name:      "no burst",
+			file:      `{"keys": [{"id": "k1", "rateLimit": 0.5}]}`,
+			wantLimit: &config.Limit{Rate: 0.5, Burst: 1},
+		},
+		{
+			name:      "burst",
+			file:      `{"keys": [{"id": "k1", "rateLimit": 5, "burst": 20}]}`,
+			wantLimit: &config.Limit{Rate: 5, Burst: 20},
+		},
+		{
+			name: "no limit",
+			file: `{"keys": [{"id": "k1", "burst": 20}]}`,
+		},
+		{
+			name:    "negative rate",
+			file:    `{"keys": [{"id": "k1", "rateLimit": -1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative burst",
+			file:    `{"keys": [{"id": "k1", "rateLimit": 1, "burst": -1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			file:    `{"keys": {}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "apikeys.json")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := Open(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Open() = nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() = %v", err)
+			}
+
+			got := s.keys["k1"].Limit()
+			switch {
+			case tt.wantLimit == nil && got != nil:
+				t.Errorf("Limit() = %+v, want nil", got)
+			case tt.wantLimit != nil && (got == nil || *got != *tt.wantLimit):
+				t.Errorf("Limit() = %+v, want %+v", got, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A key minted by another process, e.g. from the command line.
+	other, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, token, err := other.Mint(&MintRequest{Name: "cli", Scopes: []string{auth.ScopeTicketsRead}})
+	if err != nil {
+		t.Fatalf("Mint() = %v", err)
+	}
+
+	s.checked = time.Now().Add(-reloadInterval)
+	if _, err := s.Verify(t.Context(), token); err != nil {
+		t.Errorf("Verify() after reload = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Package audit records who read tickets and reports through the API.
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/httperr"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/logging"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// Entry records a call.
+type Entry struct {
+	Time time.Time `json:"time"`
+
+	// Actor is the user ID of the caller, KeyID the API key it used.
+	// Both are empty while authentication is disabled.
+	Actor string `json:"actor,omitempty"`
+	KeyID string `json:"keyId,omitempty"`
+	IP    string `json:"ip"`
+
+	// RequestID correlates the entry with the access and service logs.
+	RequestID string `json:"requestId,omitempty"`
+
+	Method string     `json:"method"`
+	Route  string     `json:"route"`
+	Query  url.Values `json:"query,omitempty"`
+
+	// Rows is the number of tickets the call returned or exported.
+	Rows int64 `json:"rows"`
+
+	// Status is the HTTP status and Result the gRPC code of the call.
+	Status   int    `json:"status"`
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type recorderKey struct{}
+
+type recorder struct {
+	rows atomic.Int64
+}
+
+// AddRows adds n to the rows of the call audited in ctx, if any.
+func AddRows(ctx context.Context, n int) {
+	if r, ok := ctx.Value(recorderKey{}).(*recorder); ok {
+		r.rows.Add(int64(n))
+	}
+}
+
+// redacted are query parameters not written to the log.
+var redacted = []string{"access_token"}
+
+// Middleware records the calls of the routes it wraps to log. It must run
+// after authentication to know the actor.
+func Middleware(log *Log, zlog *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			rec := new(recorder)
+			c.SetRequest(req.WithContext(context.WithValue(req.Context(), recorderKey{}, rec)))
+
+			err := next(c)
+
+			query := c.QueryParams()
+			for _, p := range redacted {
+				if query.Has(p) {
+					query = cloneValues(query)
+					query.Set(p, "REDACTED")
+				}
+			}
+
+			e := &Entry{
+				Time:      start.UTC(),
+				IP:        c.RealIP(),
+				RequestID: logging.RequestIDFromContext(req.Context()),
+				Method:    req.Method,
+				Route:     c.Path(),
+				Query:     query,
+				Rows:      rec.rows.Load(),
+				Status:    c.Response().Status,
+				Result:    codes.OK.String(),
+				Duration:  time.Since(start).String(),
+			}
+			if id, ok := auth.FromContext(req.Context()); ok {
+				e.Actor, e.KeyID = id.UserID, id.KeyID
+			}
+			if err != nil {
+				s := httperr.Status(err)
+				e.Result, e.Error = s.Code().String(), s.Message()
+				e.Status = httperr.HTTPCode(err)
+			}
+			if e.Status == 0 {
+				e.Status = http.StatusOK
+			}
+
+			if err := log.Append(e); err != nil {
+				zlog.Error("failed to append audit entry", zap.Error(err), zap.Any("entry", e))
+			}
+
+			return err
+		}
+	}
+}
+
+func cloneValues(v url.Values) url.Values {
+	c := make(url.Values, len(v))
+	for k, vs := range v {
+		c[k] = append([]string(nil), vs...)
+	}
+	return c
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	mw := Middleware(log, zap.NewNop())
+
+	e.GET("/v1/tickets", func(c echo.Context) error {
+		AddRows(c.Request().Context(), 2)
+		AddRows(c.Request().Context(), 3)
+		return c.NoContent(http.StatusOK)
+	}, withIdentity, mw)
+	e.GET("/v1/reports", func(c echo.Context) error {
+		return status.Error(codes.PermissionDenied, "Permission denied.")
+	}, withIdentity, mw)
+
+	serve := func(target, xff string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set(echo.HeaderXForwardedFor, xff)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	serve("/v1/tickets?status=OPEN&access_token=secret", "198.51.100.1")
+	serve("/v1/reports", "198.51.100.1")
+
+	entries, err := log.Query(&Filter{})
+	if err != nil {
+		t.Fatalf("Query() = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Query() returned %d entries, want 2", len(entries))
+	}
+
+	denied, listed := entries[0], entries[1]
+	for _, e := range entries {
+		if e.IP != "203.0.113.7" {
+			t.Errorf("IP = %s, want the peer address 203.0.113.7", e.IP)
+		}
+		if e.Actor != "1001" || e.KeyID != "k1" {
+			t.Errorf("Actor, KeyID = %q, %q, want 1001, k1", e.Actor, e.KeyID)
+		}
+	}
+
+	if listed.Route != "/v1/tickets" || listed.Rows != 5 || listed.Status != http.StatusOK || listed.Result != "OK" {
+		t.Errorf("entry = %+v, want route /v1/tickets, 5 rows, status 200 and OK", listed)
+	}
+	if got := listed.Query.Get("access_token"); got != "REDACTED" {
+		t.Errorf("access_token = %q, want REDACTED", got)
+	}
+	if got := listed.Query.Get("status"); got != "OPEN" {
+		t.Errorf("status = %q, want OPEN", got)
+	}
+
+	if denied.Status != http.StatusForbidden || denied.Result != "PermissionDenied" || denied.Error != "Permission denied." {
+		t.Errorf("entry = %+v, want status 403, PermissionDenied and its message", denied)
+	}
+}
+
+func withIdentity(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		c.SetRequest(req.WithContext(auth.NewContext(req.Context(), &auth.Identity{UserID: "1001", KeyID: "k1"})))
+		return next(c)
+	}
+}
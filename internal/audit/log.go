@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Log is an append-only JSON lines file of entries.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// Open opens the log at path, creating it if needed.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{path: path, f: f}, nil
+}
+
+// Append writes e to the log.
+func (l *Log) Append(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.f.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the log.
+func (l *Log) Close() error {
+	return l.f.Close()
+}
+
+// Filter selects entries of the log. Empty fields match anything.
+type Filter struct {
+	Actor string    `query:"actor"`
+	KeyID string    `query:"keyId"`
+	Route string    `query:"route"`
+	Since time.Time `query:"since"`
+	Until time.Time `query:"until"`
+
+	// Limit is the maximum number of entries returned, 100 by default.
+	Limit int `query:"limit"`
+}
+
+func (f *Filter) match(e *Entry) bool {
+	switch {
+	case f.Actor != "" && f.Actor != e.Actor:
+		return false
+	case f.KeyID != "" && f.KeyID != e.KeyID:
+		return false
+	case f.Route != "" && f.Route != e.Route:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query returns the newest entries matching f, newest first.
+func (l *Log) Query(f *Filter) ([]*Entry, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	limit = min(limit, maxLimit)
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	// Keep the last limit matches in a ring.
+	ring := make([]*Entry, limit)
+	var n int
+
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		e := new(Entry)
+		if err := json.Unmarshal(sc.Bytes(), e); err != nil {
+			continue
+		}
+		if f.match(e) {
+			ring[n%limit] = e
+			n++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	entries := make([]*Entry, 0, min(n, limit))
+	for i := n - 1; i >= 0 && i >= n-limit; i-- {
+		entries = append(entries, ring[i%limit])
+	}
+
+	return entries, nil
+}
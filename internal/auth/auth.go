@@ -0,0 +1,69 @@
+// Package auth authenticates the callers of the API.
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+)
+
+// Scopes granted to API keys.
+const (
+	ScopeTicketsRead   = "tickets:read"
+	ScopeReportsRead   = "reports:read"
+	ScopeExportsCreate = "exports:create"
+)
+
+// Scopes are the scopes an API key can be granted.
+var Scopes = []string{ScopeTicketsRead, ScopeReportsRead, ScopeExportsCreate}
+
+// Roles granted by the identity provider.
+const (
+	RoleEmployee          = "employee"
+	RoleDepartmentManager = "department_manager"
+	RoleBranchManager     = "branch_manager"
+	RoleIT                = "it"
+	RoleAdmin             = "admin"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// UserID is the employee number of the caller.
+	UserID     string   `json:"userId"`
+	Department string   `json:"department,omitempty"`
+	Branch     string   `json:"branch,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+
+	// KeyID is the ID of the API key the request was authenticated with,
+	// and Scopes the scopes the key grants.
+	KeyID  string   `json:"keyId,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Limit is the rate limit of the identity in each route group, e.g.
+	// the limit of its API key. It is nil if the group limits apply.
+	Limit *config.Limit `json:"-"`
+}
+
+// HasRole reports whether the identity has any of the roles.
+func (id *Identity) HasRole(roles ...string) bool {
+	for _, r := range roles {
+		if slices.Contains(id.Roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+)
+
+// Config configures bearer token authentication.
+// Tokens are verified with the keys of JWKSFile, JWKSURL and StaticKeys.
+type Config struct {
+	// JWKSFile is a JSON Web Key Set on disk.
+	JWKSFile string `json:"jwksFile"`
+
+	// JWKSURL is fetched at start and every RefreshInterval, and again
+	// when a token is signed by an unknown key.
+	JWKSURL         string          `json:"jwksUrl"`
+	RefreshInterval config.Duration `json:"refreshInterval"`
+
+	// StaticKeys are meant for offline testing. They cannot be combined
+	// with JWKSURL unless Dev is set, so that a test secret is not accepted
+	// next to the identity provider.
+	StaticKeys []StaticKey `json:"staticKeys"`
+
+	// Dev allows StaticKeys next to JWKSURL. Never set it in production:
+	// anyone holding a static secret can mint tokens with any role.
+	Dev bool `json:"dev"`
+
+	// Issuer and Audience must match the iss and aud claims if set.
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway config.Duration `json:"leeway"`
+
+	Claims ClaimNames `json:"claims"`
+}
+
+// StaticKey is a verification key given in the config.
+type StaticKey struct {
+	// ID matches the kid header of tokens. A token without kid is
+	// verified with the only key, if there is a single one.
+	ID string `json:"kid"`
+
+	// PublicKeyFile is a PEM encoded RSA, ECDSA or Ed25519 public key.
+	PublicKeyFile string `json:"publicKeyFile"`
+
+	// Secret verifies HS256 tokens.
+	Secret string `json:"secret"`
+}
+
+// ClaimNames are the claims identities are read from.
+type ClaimNames struct {
+	UserID     string `json:"userId"`
+	Department string `json:"department"`
+	Branch     string `json:"branch"`
+
+	// Roles is an array of strings or a space separated string.
+	Roles string `json:"roles"`
+}
+
+// LoadConfig reads the authentication config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth config: %w", err)
+	}
+
+	cfg := new(Config)
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse auth config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config and fills in defaults.
+func (c *Config) Validate() error {
+	if c.JWKSFile == "" && c.JWKSURL == "" && len(c.StaticKeys) == 0 {
+		return errors.New("no jwksFile, jwksUrl or staticKeys")
+	}
+	if c.JWKSURL != "" {
+		u, err := url.Parse(c.JWKSURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("jwksUrl must be an http or https URL")
+		}
+		if len(c.StaticKeys) > 0 && !c.Dev {
+			return errors.New("staticKeys cannot be combined with jwksUrl unless dev is set")
+		}
+	}
+	if c.RefreshInterval == 0 {
+		c.RefreshInterval = config.Duration(time.Hour)
+	}
+	if c.Leeway == 0 {
+		c.Leeway = config.Duration(30 * time.Second)
+	}
+
+	for i, k := range c.StaticKeys {
+		if (k.PublicKeyFile == "") == (k.Secret == "") {
+			return fmt.Errorf("static key %d: set exactly one of publicKeyFile and secret", i+1)
+		}
+	}
+
+	if c.Claims.UserID == "" {
+		c.Claims.UserID = "sub"
+	}
+	if c.Claims.Department == "" {
+		c.Claims.Department = "department"
+	}
+	if c.Claims.Branch == "" {
+		c.Claims.Branch = "branch"
+	}
+	if c.Claims.Roles == "" {
+		c.Claims.Roles = "roles"
+	}
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// minRefetch limits how often an unknown kid fetches the JWKS URL again.
+const minRefetch = time.Minute
+
+// jwk is a JSON Web Key holding a public key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signing keys of a JSON Web Key Set by kid.
+// Keys of unsupported types are skipped.
+func parseJWKS(b []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// keySet holds the keys tokens are verified with.
+type keySet struct {
+	url    string
+	client *http.Client
+	zlog   *zap.Logger
+
+	// static keys come from the config and the JWKS file.
+	static map[string]any
+
+	mu      sync.RWMutex
+	remote  map[string]any
+	fetched time.Time
+}
+
+func newKeySet(cfg *Config, zlog *zap.Logger) (*keySet, error) {
+	ks := &keySet{
+		url:    cfg.JWKSURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+		zlog:   zlog,
+		static: make(map[string]any),
+	}
+
+	if cfg.JWKSFile != "" {
+		b, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks file: %w", err)
+		}
+		keys, err := parseJWKS(b)
+		if err != nil {
+			return nil, err
+		}
+		for kid, key := range keys {
+			ks.static[kid] = key
+		}
+	}
+
+	for _, k := range cfg.StaticKeys {
+		if k.Secret != "" {
+			ks.static[k.ID] = []byte(k.Secret)
+			continue
+		}
+
+		b, err := os.ReadFile(k.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		key, err := parsePublicKeyPEM(b)
+		if err != nil {
+			return nil, fmt.Errorf("static key %q: %w", k.ID, err)
+		}
+		ks.static[k.ID] = key
+	}
+
+	return ks, nil
+}
+
+func parsePublicKeyPEM(b []byte) (any, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(b); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(b); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported public key")
+}
+
+// refresh fetches the keys of the JWKS URL.
+func (ks *keySet) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch jwks: %s", resp.Status)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read jwks: %w", err)
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.remote, ks.fetched = keys, time.Now()
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// run refreshes the remote keys every interval until ctx is done.
+func (ks *keySet) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.refresh(ctx); err != nil && ctx.Err() == nil {
+				ks.zlog.Error("failed to refresh jwks", zap.Error(err))
+			}
+		}
+	}
+}
+
+// lookup returns the key with the given kid. An empty kid selects the only
+// key, if there is a single one. Unknown kids fetch the JWKS URL again, as
+// the provider may have rotated its keys.
+func (ks *keySet) lookup(ctx context.Context, kid string) (any, bool) {
+	if key, ok := ks.find(kid); ok {
+		return key, true
+	}
+
+	if ks.url == "" {
+		return nil, false
+	}
+
+	// Claim the refetch so a burst of bad tokens fetches only once.
+	ks.mu.Lock()
+	stale := time.Since(ks.fetched) >= minRefetch
+	if stale {
+		ks.fetched = time.Now()
+	}
+	ks.mu.Unlock()
+	if !stale {
+		return nil, false
+	}
+
+	if err := ks.refresh(ctx); err != nil {
+		ks.zlog.Error("failed to refresh jwks", zap.Error(err))
+		return nil, false
+	}
+	return ks.find(kid)
+}
+
+func (ks *keySet) find(kid string) (any, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if kid == "" {
+		if len(ks.static)+len(ks.remote) != 1 {
+			return nil, false
+		}
+		for _, key := range ks.static {
+			return key, true
+		}
+		for _, key := range ks.remote {
+			return key, true
+		}
+	}
+
+	if key, ok := ks.static[kid]; ok {
+		return key, true
+	}
+	key, ok := ks.remote[kid]
+	return key, ok
+}
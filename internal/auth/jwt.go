@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// Authenticator verifies bearer tokens.
+type Authenticator struct {
+	keys   *keySet
+	claims ClaimNames
+	parser *jwt.Parser
+	zlog   *zap.Logger
+}
+
+// NewAuthenticator returns an Authenticator for the config. It fetches the
+// JWKS URL, if any, and refreshes it in the background until ctx is done.
+func NewAuthenticator(ctx context.Context, cfg *Config, zlog *zap.Logger) (*Authenticator, error) {
+	if cfg == nil {
+		return nil, errors.New("config is nil")
+	}
+	if zlog == nil {
+		return nil, errors.New("zlog is nil")
+	}
+	zlog = zlog.With(zap.String("component", "auth"))
+
+	keys, err := newKeySet(cfg, zlog)
+	if err != nil {
+		return nil, err
+	}
+	if keys.url != "" {
+		if err := keys.refresh(ctx); err != nil {
+			return nil, err
+		}
+		go keys.run(ctx, time.Duration(cfg.RefreshInterval))
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{
+			"RS256", "RS384", "RS512",
+			"PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512",
+			"EdDSA", "HS256",
+		}),
+		jwt.WithLeeway(time.Duration(cfg.Leeway)),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &Authenticator{
+		keys:   keys,
+		claims: cfg.Claims,
+		parser: jwt.NewParser(opts...),
+		zlog:   zlog,
+	}, nil
+}
+
+// Verify verifies the token and returns the identity it carries.
+func (a *Authenticator) Verify(ctx context.Context, token string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := a.keys.lookup(ctx, kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		// A key only verifies the algorithms of its type, so a public key
+		// is never used as an HMAC secret.
+		if !keyFits(t.Method, key) {
+			return nil, fmt.Errorf("key %q does not verify %s", kid, t.Method.Alg())
+		}
+		return key, nil
+	})
+	if err != nil {
+		a.zlog.Info("rejected token", zap.Error(err))
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, Unauthenticated("TOKEN_EXPIRED", "Token has expired.")
+		}
+		return nil, Unauthenticated("INVALID_TOKEN", "Token is invalid.")
+	}
+
+	id := &Identity{
+		UserID:     stringClaim(claims, a.claims.UserID),
+		Department: stringClaim(claims, a.claims.Department),
+		Branch:     stringClaim(claims, a.claims.Branch),
+		Roles:      listClaim(claims, a.claims.Roles),
+	}
+	if id.UserID == "" {
+		return nil, Unauthenticated("INVALID_TOKEN", "Token has no user ID.")
+	}
+
+	return id, nil
+}
+
+// keyFits reports whether key is of the type the signing method verifies.
+func keyFits(method jwt.SigningMethod, key any) bool {
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := key.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := key.(*ecdsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodEd25519:
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	default:
+		return false
+	}
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
+
+func listClaim(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
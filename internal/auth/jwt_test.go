@@ -0,0 +1,481 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const (
+	testIssuer   = "https://login.example.com/"
+	testAudience = "helpdesk-dashboard"
+	testSecret   = "only-for-offline-testing"
+)
+
+// testKeys are generated once, RSA keys being slow to generate.
+var testKeys = sync.OnceValue(func() (keys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+	ed  ed25519.PrivateKey
+}) {
+	var err error
+	if keys.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		panic(err)
+	}
+	if keys.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		panic(err)
+	}
+	if _, keys.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		panic(err)
+	}
+	return keys
+})
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// jwksOf returns the JSON Web Key Set of the public keys by kid.
+func jwksOf(t *testing.T, keys map[string]any) []byte {
+	t.Helper()
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: kid, Use: "sig", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(k.X.FillBytes(make([]byte, 32))), Y: b64(k.Y.FillBytes(make([]byte, 32)))})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "OKP", Kid: kid, Crv: "Ed25519", X: b64(k)})
+		default:
+			t.Fatalf("unsupported key %T", key)
+		}
+	}
+
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newTestAuthenticator(t *testing.T, cfg *Config) *Authenticator {
+	t.Helper()
+
+	cfg.Issuer, cfg.Audience = testIssuer, testAudience
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuthenticator(t.Context(), cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":        testIssuer,
+		"aud":        testAudience,
+		"sub":        "1001",
+		"iat":        now.Unix(),
+		"exp":        now.Add(time.Hour).Unix(),
+		"department": "IT",
+		"branch":     "HQ",
+		"roles":      []string{RoleEmployee, RoleIT},
+	}
+}
+
+// sign signs claims with method and key, setting kid unless it is empty.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// withClaims returns the valid claims changed by f.
+func withClaims(f func(jwt.MapClaims)) jwt.MapClaims {
+	c := validClaims()
+	f(c)
+	return c
+}
+
+// wantStatus checks that err is an Unauthenticated status with the reason
+// and message.
+func wantStatus(t *testing.T, err error, reason, msg string) {
+	t.Helper()
+
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.Unauthenticated {
+		t.Fatalf("error = %v, want Unauthenticated", err)
+	}
+	if s.Message() != msg {
+		t.Errorf("message = %q, want %q", s.Message(), msg)
+	}
+	for _, d := range s.Details() {
+		if info, ok := d.(*edpb.ErrorInfo); ok && info.Reason != reason {
+			t.Errorf("reason = %s, want %s", info.Reason, reason)
+		}
+	}
+}
+
+func TestAuthenticatorVerify(t *testing.T) {
+	a := newTestAuthenticator(t, &Config{
+		StaticKeys: []StaticKey{{ID: "local", Secret: testSecret}},
+		Claims:     ClaimNames{UserID: "sub"},
+	})
+	secret := []byte(testSecret)
+
+	tests := []struct {
+		name       string
+		token      string
+		want       *Identity
+		wantReason string
+		wantMsg    string
+	}{
+		{
+			name:  "valid",
+			token: sign(t, jwt.SigningMethodHS256, "local", secret, validClaims()),
+			want:  &Identity{UserID: "1001", Department: "IT", Branch: "HQ", Roles: []string{RoleEmployee, RoleIT}},
+		},
+		{
+			name:  "single key without kid",
+			token: sign(t, jwt.SigningMethodHS256, "", secret, validClaims()),
+			want:  &Identity{UserID: "1001", Department: "IT", Branch: "HQ", Roles: []string{RoleEmployee, RoleIT}},
+		},
+		{
+			name: "space separated roles and numeric user id",
+			token: sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) {
+				c["sub"] = 1001
+				c["roles"] = "admin it"
+				delete(c, "branch")
+			})),
+			want: &Identity{UserID: "1001", Department: "IT", Roles: []string{RoleAdmin, RoleIT}},
+		},
+		{
+			name:       "expired",
+			token:      sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			wantReason: "TOKEN_EXPIRED",
+			wantMsg:    "Token has expired.",
+		},
+		{
+			name:  "expired within leeway",
+			token: sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() })),
+			want:  &Identity{UserID: "1001", Department: "IT", Branch: "HQ", Roles: []string{RoleEmployee, RoleIT}},
+		},
+		{
+			name:       "no expiry",
+			token:      sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "not yet valid",
+			token:      sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "other issuer",
+			token:      sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com/" })),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "other audience",
+			token:      sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { c["aud"] = "payroll" })),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "wrong secret",
+			token:      sign(t, jwt.SigningMethodHS256, "local", []byte("guessed"), validClaims()),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "unknown kid",
+			token:      sign(t, jwt.SigningMethodHS256, "other", secret, validClaims()),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "unsigned",
+			token:      sign(t, jwt.SigningMethodNone, "local", jwt.UnsafeAllowNoneSignatureType, validClaims()),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "malformed",
+			token:      "not.a.token",
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token is invalid.",
+		},
+		{
+			name:       "no user id",
+			token:      sign(t, jwt.SigningMethodHS256, "local", secret, withClaims(func(c jwt.MapClaims) { delete(c, "sub") })),
+			wantReason: "INVALID_TOKEN",
+			wantMsg:    "Token has no user ID.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.Verify(t.Context(), tt.token)
+			if tt.want == nil {
+				wantStatus(t, err, tt.wantReason, tt.wantMsg)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if id.UserID != tt.want.UserID || id.Department != tt.want.Department ||
+				id.Branch != tt.want.Branch || !slices.Equal(id.Roles, tt.want.Roles) {
+				t.Errorf("Verify() = %+v, want %+v", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorKeyTypes(t *testing.T) {
+	keys := testKeys()
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	err := os.WriteFile(jwksFile, jwksOf(t, map[string]any{
+		"rsa": &keys.rsa.PublicKey,
+		"ec":  &keys.ec.PublicKey,
+		"ed":  keys.ed.Public(),
+	}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := newTestAuthenticator(t, &Config{
+		JWKSFile:   jwksFile,
+		StaticKeys: []StaticKey{{ID: "hmac", Secret: testSecret}},
+	})
+
+	// The public key as an attacker would use it for an HMAC secret.
+	rsaDER, err := x509.MarshalPKIXPublicKey(&keys.rsa.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaDER})
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		key    any
+		wantOK bool
+	}{
+		{name: "RS256", method: jwt.SigningMethodRS256, kid: "rsa", key: keys.rsa, wantOK: true},
+		{name: "RS512", method: jwt.SigningMethodRS512, kid: "rsa", key: keys.rsa, wantOK: true},
+		{name: "PS256", method: jwt.SigningMethodPS256, kid: "rsa", key: keys.rsa, wantOK: true},
+		{name: "ES256", method: jwt.SigningMethodES256, kid: "ec", key: keys.ec, wantOK: true},
+		{name: "EdDSA", method: jwt.SigningMethodEdDSA, kid: "ed", key: keys.ed, wantOK: true},
+		{name: "HS256", method: jwt.SigningMethodHS256, kid: "hmac", key: []byte(testSecret), wantOK: true},
+
+		{name: "HS256 with the RSA public key as secret", method: jwt.SigningMethodHS256, kid: "rsa", key: rsaPEM},
+		{name: "HS256 with the RSA modulus as secret", method: jwt.SigningMethodHS256, kid: "rsa", key: keys.rsa.N.Bytes()},
+		{name: "RS256 naming the HMAC key", method: jwt.SigningMethodRS256, kid: "hmac", key: keys.rsa},
+		{name: "ES256 naming the RSA key", method: jwt.SigningMethodES256, kid: "rsa", key: keys.ec},
+		{name: "EdDSA naming the EC key", method: jwt.SigningMethodEdDSA, kid: "ec", key: keys.ed},
+		{name: "HS384 is not accepted", method: jwt.SigningMethodHS384, kid: "hmac", key: []byte(testSecret)},
+		{name: "RS256 signed by another key", method: jwt.SigningMethodRS256, kid: "rsa", key: mustRSAKey(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := sign(t, tt.method, tt.kid, tt.key, validClaims())
+
+			_, err := a.Verify(t.Context(), token)
+			if tt.wantOK {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				return
+			}
+			wantStatus(t, err, "INVALID_TOKEN", "Token is invalid.")
+		})
+	}
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestAuthenticatorRefetchesUnknownKid(t *testing.T) {
+	keys := testKeys()
+
+	var (
+		jwks    atomic.Pointer[[]byte]
+		fetches atomic.Int32
+	)
+	publish := func(set map[string]any) {
+		b := jwksOf(t, set)
+		jwks.Store(&b)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(*jwks.Load())
+	}))
+	t.Cleanup(srv.Close)
+
+	publish(map[string]any{"k1": &keys.rsa.PublicKey})
+	a := newTestAuthenticator(t, &Config{
+		JWKSURL:         srv.URL,
+		RefreshInterval: config.Duration(time.Hour),
+	})
+	// expire makes the last fetch old enough for an unknown kid to refetch.
+	expire := func() {
+		a.keys.mu.Lock()
+		a.keys.fetched = time.Now().Add(-2 * minRefetch)
+		a.keys.mu.Unlock()
+	}
+
+	steps := []struct {
+		name        string
+		rotate      map[string]any
+		expire      bool
+		kid         string
+		key         any
+		wantOK      bool
+		wantFetches int32
+	}{
+		{name: "known kid", kid: "k1", key: keys.rsa, wantOK: true, wantFetches: 1},
+		{name: "rotated kid right after a fetch", rotate: map[string]any{"k2": &keys.ec.PublicKey}, kid: "k2", key: keys.ec, wantFetches: 1},
+		{name: "rotated kid once the fetch is old", expire: true, kid: "k2", key: keys.ec, wantOK: true, wantFetches: 2},
+		{name: "unknown kid right after a refetch", kid: "k3", key: keys.ed, wantFetches: 2},
+		{name: "retired kid", kid: "k1", key: keys.rsa, wantFetches: 2},
+		{name: "unknown kid once the fetch is old", expire: true, kid: "k3", key: keys.ed, wantFetches: 3},
+		{name: "no refetch storm", kid: "k4", key: keys.ed, wantFetches: 3},
+	}
+
+	for _, st := range steps {
+		if st.rotate != nil {
+			publish(st.rotate)
+		}
+		if st.expire {
+			expire()
+		}
+
+		method := map[string]jwt.SigningMethod{"k1": jwt.SigningMethodRS256, "k2": jwt.SigningMethodES256}[st.kid]
+		if method == nil {
+			method = jwt.SigningMethodEdDSA
+		}
+		_, err := a.Verify(t.Context(), sign(t, method, st.kid, st.key, validClaims()))
+		if (err == nil) != st.wantOK {
+			t.Errorf("%s: Verify() error = %v, want ok %v", st.name, err, st.wantOK)
+		}
+		if got := fetches.Load(); got != st.wantFetches {
+			t.Errorf("%s: %d fetches, want %d", st.name, got, st.wantFetches)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "jwks url",
+			cfg:  Config{JWKSURL: "https://login.example.com/jwks.json"},
+		},
+		{
+			name: "static keys",
+			cfg:  Config{StaticKeys: []StaticKey{{ID: "local", Secret: testSecret}}},
+		},
+		{
+			name:    "static keys next to the jwks url",
+			cfg:     Config{JWKSURL: "https://login.example.com/jwks.json", StaticKeys: []StaticKey{{ID: "local", Secret: testSecret}}},
+			wantErr: true,
+		},
+		{
+			name: "static keys next to the jwks url in dev",
+			cfg:  Config{JWKSURL: "https://login.example.com/jwks.json", StaticKeys: []StaticKey{{ID: "local", Secret: testSecret}}, Dev: true},
+		},
+		{
+			name:    "no keys",
+			cfg:     Config{},
+			wantErr: true,
+		},
+		{
+			name:    "jwks url without http",
+			cfg:     Config{JWKSURL: "file:///etc/jwks.json"},
+			wantErr: true,
+		},
+		{
+			name:    "static key with secret and public key",
+			cfg:     Config{StaticKeys: []StaticKey{{ID: "local", Secret: testSecret, PublicKeyFile: "key.pem"}}},
+			wantErr: true,
+		},
+		{
+			name:    "static key without secret or public key",
+			cfg:     Config{StaticKeys: []StaticKey{{ID: "local"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadExampleConfigs(t *testing.T) {
+	for _, name := range []string{"auth.example.json", "auth.offline.example.json"} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadConfig(filepath.Join("..", "..", "configs", name)); err != nil {
+				t.Errorf("LoadConfig() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// HeaderAPIKey carries an API key instead of the Authorization header.
+const HeaderAPIKey = "X-API-Key"
+
+// KeyPrefix starts every API key, telling them apart from bearer tokens.
+const KeyPrefix = "hdk_"
+
+// Verifier verifies a credential and returns the identity it carries.
+type Verifier interface {
+	Verify(ctx context.Context, credential string) (*Identity, error)
+}
+
+// Middleware authenticates requests and stores the identity in the request
+// context. Bearer tokens are verified by tokens, API keys by keys; either
+// may be nil to reject that kind of credential.
+func Middleware(tokens, keys Verifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			credential, ok := bearerToken(req.Header.Get(echo.HeaderAuthorization))
+			if !ok {
+				credential = req.Header.Get(HeaderAPIKey)
+				ok = credential != ""
+			}
+			if !ok && isStream(req) {
+				// EventSource and WebSocket clients in browsers cannot set
+				// headers, so streams may pass the token as a parameter.
+				credential = req.URL.Query().Get("access_token")
+				ok = credential != ""
+			}
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return Unauthenticated("MISSING_TOKEN", "Request must have a bearer token or an API key.")
+			}
+
+			v := tokens
+			if strings.HasPrefix(credential, KeyPrefix) {
+				v = keys
+			}
+			if v == nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return Unauthenticated("INVALID_TOKEN", "Token is invalid.")
+			}
+
+			id, err := v.Verify(req.Context(), credential)
+			if err != nil {
+				if status.Code(err) == codes.Unauthenticated {
+					c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				}
+				return err
+			}
+
+			c.SetRequest(req.WithContext(NewContext(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// RequireRole rejects requests whose identity has none of the roles.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, ok := FromContext(c.Request().Context())
+			if !ok {
+				return Unauthenticated("MISSING_TOKEN", "Request must have a bearer token or an API key.")
+			}
+			if !id.HasRole(roles...) {
+				return permissionDenied("MISSING_ROLE", "roles", roles)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireScope rejects requests authenticated by an API key without the
+// scope. Users are authorized by their role instead, and requests without
+// an identity are left to the authentication middleware.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, ok := FromContext(c.Request().Context())
+			if ok && id.KeyID != "" && !slices.Contains(id.Scopes, scope) {
+				return permissionDenied("MISSING_SCOPE", "scope", []string{scope})
+			}
+			return next(c)
+		}
+	}
+}
+
+func isStream(req *http.Request) bool {
+	return strings.Contains(req.Header.Get(echo.HeaderAccept), "text/event-stream") ||
+		strings.EqualFold(req.Header.Get(echo.HeaderUpgrade), "websocket")
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Unauthenticated returns an Unauthenticated status with the reason.
+func Unauthenticated(reason, msg string) error {
+	s, _ := status.New(codes.Unauthenticated, msg).
+		WithDetails(&edpb.ErrorInfo{
+			Reason: reason,
+			Domain: "helpdesk",
+		})
+	return s.Err()
+}
+
+func permissionDenied(reason, key string, values []string) error {
+	s, _ := status.New(codes.PermissionDenied, "Permission denied.").
+		WithDetails(&edpb.ErrorInfo{
+			Reason:   reason,
+			Domain:   "helpdesk",
+			Metadata: map[string]string{key: strings.Join(values, ",")},
+		})
+	return s.Err()
+}
@@ -0,0 +1,475 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/cors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config is the configuration of the API server. Fields are read from a
+// JSON file, then from the environment variables and flags named by their
+// env and flag tags.
+type Config struct {
+	// TimeZone is the zone the database records its timestamps in.
+	TimeZone string `json:"timeZone" env:"TIME_ZONE" flag:"time-zone" usage:"IANA time zone of the database timestamps"`
+
+	DB   DB   `json:"db"`
+	HTTP HTTP `json:"http"`
+	Log  Log  `json:"log"`
+
+	Health Health `json:"health"`
+
+	Watch       Watch       `json:"watch"`
+	ReportCache ReportCache `json:"reportCache"`
+	Metrics     Metrics     `json:"metrics"`
+	Tracing     Tracing     `json:"tracing"`
+	Export      Export      `json:"export"`
+	CORS        CORS        `json:"cors"`
+
+	RateLimits *RateLimits `json:"rateLimits"`
+	Auth       Auth        `json:"auth"`
+
+	// Files configuring optional features; a feature is off if its file
+	// is not set.
+	SchedulesFile    string `json:"schedulesFile" env:"SCHEDULES_FILE" flag:"schedules-file" usage:"scheduled reports config"`
+	WebhooksFile     string `json:"webhooksFile" env:"WEBHOOKS_FILE" flag:"webhooks-file" usage:"webhooks config"`
+	AuthFile         string `json:"authFile" env:"AUTH_FILE" flag:"auth-file" usage:"JWT authentication config"`
+	APIKeysFile      string `json:"apiKeysFile" env:"APIKEYS_FILE" flag:"apikeys-file" usage:"API keys store"`
+	AuditLogFile     string `json:"auditLogFile" env:"AUDIT_LOG_FILE" flag:"audit-log-file" usage:"audit log, JSON lines"`
+	CacheControlFile string `json:"cacheControlFile" env:"CACHE_CONTROL_FILE" flag:"cache-control-file" usage:"Cache-Control header per route"`
+}
+
+// DB configures the SQL Server connection.
+type DB struct {
+	Host     string `json:"host" env:"DB_HOST" flag:"db-host" usage:"database host"`
+	Port     int    `json:"port" env:"DB_PORT" flag:"db-port" usage:"database port"`
+	Name     string `json:"name" env:"DB_NAME" flag:"db-name" usage:"database name"`
+	User     string `json:"user" env:"DB_USER" flag:"db-user" usage:"database user"`
+	Password string `json:"password" env:"DB_PASSWORD" secret:"true"`
+
+	// Encrypt is true, false or disable. false still encrypts the login.
+	Encrypt string `json:"encrypt" env:"DB_ENCRYPT" flag:"db-encrypt" usage:"TLS: true, false or disable"`
+
+	// TrustServerCertificate skips the verification of the certificate of
+	// the server. Set Certificate to trust a private CA instead.
+	TrustServerCertificate bool   `json:"trustServerCertificate" env:"DB_TRUST_SERVER_CERTIFICATE" flag:"db-trust-server-certificate" usage:"skip server certificate verification"`
+	Certificate            string `json:"certificate" env:"DB_CERTIFICATE" flag:"db-certificate" usage:"CA certificate file of the server"`
+	HostNameInCertificate  string `json:"hostNameInCertificate" env:"DB_HOST_NAME_IN_CERTIFICATE" flag:"db-host-name-in-certificate" usage:"expected host name of the server certificate"`
+
+	AppName           string   `json:"appName" env:"DB_APP_NAME" flag:"db-app-name" usage:"application name reported to the server"`
+	DialTimeout       Duration `json:"dialTimeout" env:"DB_DIAL_TIMEOUT" flag:"db-dial-timeout" usage:"timeout of dialing the server"`
+	ConnectionTimeout Duration `json:"connectionTimeout" env:"DB_CONNECTION_TIMEOUT" flag:"db-connection-timeout" usage:"timeout of connecting and logging in"`
+
+	// Pool settings of database/sql.
+	MaxOpenConns    int      `json:"maxOpenConns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections, 0 is unlimited"`
+	MaxIdleConns    int      `json:"maxIdleConns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections"`
+	ConnMaxLifetime Duration `json:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a connection, 0 is unlimited"`
+	ConnMaxIdleTime Duration `json:"connMaxIdleTime" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" usage:"maximum idle time of a connection, 0 is unlimited"`
+}
+
+// HTTP configures the HTTP server.
+type HTTP struct {
+	Port              int      `json:"port" env:"PORT" flag:"port" usage:"HTTP port"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"http-read-header-timeout" usage:"timeout of reading request headers"`
+	IdleTimeout       Duration `json:"idleTimeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" usage:"keep-alive timeout"`
+	ShutdownTimeout   Duration `json:"shutdownTimeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"http-shutdown-timeout" usage:"grace period of in-flight requests on shutdown"`
+
+	// TrustedProxies are the addresses or CIDR ranges of the reverse
+	// proxies in front of the server. X-Forwarded-For is ignored unless
+	// the request comes from one of them.
+	TrustedProxies []string `json:"trustedProxies" env:"HTTP_TRUSTED_PROXIES" flag:"http-trusted-proxies" usage:"comma-separated addresses or CIDR ranges of trusted reverse proxies"`
+}
+
+// Auth configures authentication, which is set up by AuthFile and
+// APIKeysFile.
+type Auth struct {
+	// Disabled serves requests without authentication. The server refuses
+	// to start without either authentication file unless it is set.
+	Disabled bool `json:"disabled" env:"AUTH_DISABLED" flag:"auth-disabled" usage:"serve requests without authentication"`
+}
+
+// Health configures the readiness check.
+type Health struct {
+	// Timeout bounds the database checks of /readyz.
+	Timeout Duration `json:"timeout" env:"READY_TIMEOUT" flag:"ready-timeout" usage:"timeout of the readiness checks"`
+
+	// CheckView also queries the ticket view, catching missing grants or a
+	// dropped view that a ping does not.
+	CheckView bool `json:"checkView" env:"READY_CHECK_VIEW" flag:"ready-check-view" usage:"query the ticket view in readiness checks"`
+}
+
+// Log configures the logger.
+type Log struct {
+	Level    string `json:"level" env:"LOG_LEVEL" flag:"log-level" usage:"debug, info, warn or error"`
+	Encoding string `json:"encoding" env:"LOG_ENCODING" flag:"log-encoding" usage:"console or json"`
+
+	// Outputs are stdout, stderr or paths of files. Files are rotated.
+	Outputs []string `json:"outputs" env:"LOG_OUTPUTS" flag:"log-outputs" usage:"comma separated stdout, stderr or file paths"`
+
+	Sampling LogSampling `json:"sampling"`
+	Rotation LogRotation `json:"rotation"`
+}
+
+// LogSampling caps the entries logged with the same level and message each
+// second: the first Initial are logged, then every Thereafter-th. Sampling
+// is disabled if Initial is 0.
+type LogSampling struct {
+	Initial    int `json:"initial" env:"LOG_SAMPLING_INITIAL" flag:"log-sampling-initial" usage:"entries logged per second and message before sampling, 0 disables sampling"`
+	Thereafter int `json:"thereafter" env:"LOG_SAMPLING_THEREAFTER" flag:"log-sampling-thereafter" usage:"log every nth entry of a message once sampling"`
+}
+
+// LogRotation configures the rotation of log files.
+type LogRotation struct {
+	MaxSizeMB  int      `json:"maxSizeMB" env:"LOG_MAX_SIZE_MB" flag:"log-max-size-mb" usage:"size in megabytes a log file is rotated at"`
+	MaxAge     Duration `json:"maxAge" env:"LOG_MAX_AGE" flag:"log-max-age" usage:"age rotated log files are removed at, a whole number of days such as 720h, 0 keeps them"`
+	MaxBackups int      `json:"maxBackups" env:"LOG_MAX_BACKUPS" flag:"log-max-backups" usage:"number of rotated log files kept, 0 keeps all"`
+	Compress   bool     `json:"compress" env:"LOG_COMPRESS" flag:"log-compress" usage:"gzip rotated log files"`
+}
+
+// Watch configures the change detection of tickets.
+type Watch struct {
+	Interval Duration `json:"interval" env:"WATCH_INTERVAL" flag:"watch-interval" usage:"interval of polling for ticket changes"`
+	Lookback Duration `json:"lookback" env:"WATCH_LOOKBACK" flag:"watch-lookback" usage:"age of the tickets watched for changes; each poll reads the id and status of all of them"`
+}
+
+// ReportCache configures the cache of report queries.
+type ReportCache struct {
+	TTL Duration `json:"ttl" env:"REPORT_CACHE_TTL" flag:"report-cache-ttl" usage:"lifetime of cached reports, 0 disables the cache"`
+}
+
+// Metrics configures the Prometheus metrics.
+type Metrics struct {
+	Enabled bool `json:"enabled" env:"METRICS_ENABLED" flag:"metrics-enabled" usage:"serve Prometheus metrics on /metrics"`
+
+	// Addr is the address metrics are served on, apart from the API: they
+	// reveal ticket counts and pool statistics to anyone who can reach it.
+	Addr string `json:"addr" env:"METRICS_ADDR" flag:"metrics-addr" usage:"host:port of the metrics listener, keep it private"`
+
+	// OpenTicketsInterval is how often the open tickets gauge is counted.
+	OpenTicketsInterval Duration `json:"openTicketsInterval" env:"METRICS_OPEN_TICKETS_INTERVAL" flag:"metrics-open-tickets-interval" usage:"interval of counting open tickets"`
+}
+
+// Tracing exporters.
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// Tracing configures the OpenTelemetry traces.
+type Tracing struct {
+	Exporter    string  `json:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"none, stdout or otlp"`
+	Endpoint    string  `json:"endpoint" env:"TRACING_ENDPOINT" flag:"tracing-endpoint" usage:"host:port of the OTLP/HTTP collector"`
+	Insecure    bool    `json:"insecure" env:"TRACING_INSECURE" flag:"tracing-insecure" usage:"send traces to the collector over plain HTTP"`
+	ServiceName string  `json:"serviceName" env:"TRACING_SERVICE_NAME" flag:"tracing-service-name" usage:"service name of the traces"`
+	SampleRatio float64 `json:"sampleRatio" env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" usage:"ratio of the traces started here that are sampled"`
+}
+
+// Export configures exports.
+type Export struct {
+	TemplatesFile string `json:"templatesFile" env:"EXPORT_TEMPLATES_FILE" flag:"export-templates-file" usage:"export templates"`
+	PDFFontFile   string `json:"pdfFontFile" env:"PDF_FONT_FILE" flag:"pdf-font-file" usage:"TrueType font of PDF reports"`
+}
+
+// CORS selects a profile of cross-origin settings and overrides its fields.
+type CORS struct {
+	Profile       string   `json:"profile" env:"CORS_PROFILE" flag:"cors-profile" usage:"dev or prod"`
+	Origins       []string `json:"origins" env:"CORS_ORIGINS" flag:"cors-origins" usage:"comma separated allowed origins"`
+	Methods       []string `json:"methods"`
+	Headers       []string `json:"headers"`
+	ExposeHeaders []string `json:"exposeHeaders"`
+	Credentials   *bool    `json:"credentials"`
+	MaxAge        int      `json:"maxAge"`
+}
+
+// Default returns the configuration used for unset fields.
+func Default() *Config {
+	return &Config{
+		// helpdesk.DefaultTimeZone, which imports this package indirectly.
+		TimeZone: "Asia/Vientiane",
+		DB: DB{
+			Port:              1433,
+			Encrypt:           "true",
+			AppName:           "helpdesk-dashboard-api",
+			DialTimeout:       Duration(15 * time.Second),
+			ConnectionTimeout: Duration(30 * time.Second),
+			MaxOpenConns:      20,
+			MaxIdleConns:      10,
+			ConnMaxLifetime:   Duration(30 * time.Minute),
+			ConnMaxIdleTime:   Duration(5 * time.Minute),
+		},
+		HTTP: HTTP{
+			Port:              8089,
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(15 * time.Second),
+		},
+		Health: Health{
+			Timeout:   Duration(2 * time.Second),
+			CheckView: true,
+		},
+		Log: Log{
+			Level:    "info",
+			Encoding: "console",
+			Outputs:  []string{"stdout"},
+			Rotation: LogRotation{
+				MaxSizeMB:  100,
+				MaxAge:     Duration(30 * 24 * time.Hour),
+				MaxBackups: 10,
+				Compress:   true,
+			},
+		},
+		Watch: Watch{
+			Interval: Duration(15 * time.Second),
+			Lookback: Duration(7 * 24 * time.Hour),
+		},
+		Metrics: Metrics{
+			Enabled:             true,
+			Addr:                "127.0.0.1:9090",
+			OpenTicketsInterval: Duration(time.Minute),
+		},
+		Tracing: Tracing{
+			Exporter:    TracingNone,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "helpdesk-dashboard-api",
+			SampleRatio: 1,
+		},
+		ReportCache: ReportCache{
+			TTL: Duration(time.Minute),
+		},
+		CORS: CORS{
+			Profile: cors.ProfileProd,
+		},
+		RateLimits: DefaultRateLimits(),
+	}
+}
+
+// Validate checks the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("timeZone: %w", err))
+	}
+
+	if c.DB.Host == "" {
+		errs = append(errs, errors.New("db.host is empty"))
+	}
+	if c.DB.Name == "" {
+		errs = append(errs, errors.New("db.name is empty"))
+	}
+	if c.DB.Port <= 0 || c.DB.Port > 65535 {
+		errs = append(errs, errors.New("db.port must be between 1 and 65535"))
+	}
+	switch strings.ToLower(c.DB.Encrypt) {
+	case "true", "false", "disable":
+	default:
+		errs = append(errs, errors.New("db.encrypt must be true, false or disable"))
+	}
+	if c.DB.DialTimeout < 0 || c.DB.ConnectionTimeout < 0 {
+		errs = append(errs, errors.New("db timeouts must not be negative"))
+	}
+	if c.DB.MaxOpenConns < 0 || c.DB.MaxIdleConns < 0 || c.DB.ConnMaxLifetime < 0 || c.DB.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("db pool settings must not be negative"))
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		errs = append(errs, errors.New("db.maxIdleConns must not exceed db.maxOpenConns"))
+	}
+	if c.Health.Timeout <= 0 {
+		errs = append(errs, errors.New("health.timeout must be positive"))
+	}
+
+	if c.HTTP.Port <= 0 || c.HTTP.Port > 65535 {
+		errs = append(errs, errors.New("http.port must be between 1 and 65535"))
+	}
+	if _, err := c.HTTP.IPExtractor(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if c.Log.Encoding != "console" && c.Log.Encoding != "json" {
+		errs = append(errs, errors.New("log.encoding must be console or json"))
+	}
+	if len(c.Log.Outputs) == 0 {
+		errs = append(errs, errors.New("log.outputs must not be empty"))
+	}
+	if c.Log.Sampling.Initial < 0 || c.Log.Sampling.Thereafter < 0 {
+		errs = append(errs, errors.New("log.sampling must not be negative"))
+	}
+	if c.Log.Rotation.MaxSizeMB <= 0 {
+		errs = append(errs, errors.New("log.rotation.maxSizeMB must be positive"))
+	}
+	if c.Log.Rotation.MaxAge < 0 || c.Log.Rotation.MaxBackups < 0 {
+		errs = append(errs, errors.New("log.rotation must not be negative"))
+	}
+	// Rotated files are removed by whole days, and 0 keeps them.
+	if time.Duration(c.Log.Rotation.MaxAge)%(24*time.Hour) != 0 {
+		errs = append(errs, errors.New("log.rotation.maxAge must be a whole number of days"))
+	}
+
+	if c.Watch.Interval <= 0 || c.Watch.Lookback <= 0 {
+		errs = append(errs, errors.New("watch.interval and watch.lookback must be positive"))
+	}
+	if c.Metrics.Enabled {
+		if c.Metrics.OpenTicketsInterval <= 0 {
+			errs = append(errs, errors.New("metrics.openTicketsInterval must be positive"))
+		}
+		if _, port, err := net.SplitHostPort(c.Metrics.Addr); err != nil || port == "" {
+			errs = append(errs, errors.New("metrics.addr must be a host:port"))
+		} else if port == strconv.Itoa(c.HTTP.Port) {
+			errs = append(errs, errors.New("metrics.addr must not use the port of the API"))
+		}
+	}
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingStdout:
+	case TracingOTLP:
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required by the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q is not none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sampleRatio must be between 0 and 1"))
+	}
+	if c.ReportCache.TTL < 0 {
+		errs = append(errs, errors.New("reportCache.ttl must not be negative"))
+	}
+
+	if _, err := c.CORS.Resolve(); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+	hasAuth := c.AuthFile != "" || c.APIKeysFile != ""
+	if !hasAuth && !c.Auth.Disabled {
+		errs = append(errs, errors.New("authFile or apiKeysFile is required unless auth.disabled is set"))
+	}
+	if hasAuth && c.Auth.Disabled {
+		errs = append(errs, errors.New("auth.disabled cannot be combined with authFile or apiKeysFile"))
+	}
+
+	if c.RateLimits == nil {
+		c.RateLimits = DefaultRateLimits()
+	}
+	if err := c.RateLimits.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimits: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// DSN returns the connection string of the database.
+func (db *DB) DSN() string {
+	q := url.Values{}
+	q.Set("database", db.Name)
+	q.Set("encrypt", db.Encrypt)
+	q.Set("TrustServerCertificate", strconv.FormatBool(db.TrustServerCertificate))
+	if db.Certificate != "" {
+		q.Set("certificate", db.Certificate)
+	}
+	if db.HostNameInCertificate != "" {
+		q.Set("hostNameInCertificate", db.HostNameInCertificate)
+	}
+	if db.AppName != "" {
+		q.Set("app name", db.AppName)
+	}
+	if db.DialTimeout > 0 {
+		q.Set("dial timeout", strconv.Itoa(int(time.Duration(db.DialTimeout).Seconds())))
+	}
+	if db.ConnectionTimeout > 0 {
+		q.Set("connection timeout", strconv.Itoa(int(time.Duration(db.ConnectionTimeout).Seconds())))
+	}
+
+	u := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(db.User, db.Password),
+		Host:     net.JoinHostPort(db.Host, strconv.Itoa(db.Port)),
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// Addr returns the listen address of the server.
+func (h *HTTP) Addr() string {
+	return ":" + strconv.Itoa(h.Port)
+}
+
+// IPExtractor returns how the client IP of a request is found. Without
+// trusted proxies it is the peer address. Otherwise it is the last address
+// of X-Forwarded-For that is not a trusted proxy, as long as the peer is one.
+func (h *HTTP) IPExtractor() (echo.IPExtractor, error) {
+	if len(h.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// Only the configured ranges are trusted, not echo's defaults of
+	// loopback, link-local and private networks.
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, p := range h.TrustedProxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("http.trustedProxies: %q is not an address or a CIDR range", p)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			opts = append(opts, echo.TrustIPRange(&net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}))
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("http.trustedProxies: %q is not an address or a CIDR range", p)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
+
+// Resolve returns the CORS settings of the profile with the overrides.
+func (c *CORS) Resolve() (*cors.Config, error) {
+	cfg, err := cors.Profile(c.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Origins) > 0 {
+		cfg.Origins = c.Origins
+	}
+	if len(c.Methods) > 0 {
+		cfg.Methods = c.Methods
+	}
+	if len(c.Headers) > 0 {
+		cfg.Headers = c.Headers
+	}
+	if len(c.ExposeHeaders) > 0 {
+		cfg.ExposeHeaders = c.ExposeHeaders
+	}
+	if c.Credentials != nil {
+		cfg.Credentials = *c.Credentials
+	}
+	if c.MaxAge > 0 {
+		cfg.MaxAge = c.MaxAge
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
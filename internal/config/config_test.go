@@ -0,0 +1,147 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.DB.Host = "localhost"
+	cfg.DB.Name = "helpdesk"
+	cfg.Auth.Disabled = true
+	return cfg
+}
+
+func TestValidateMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics Metrics
+		wantErr string
+	}{
+		{
+			name:    "default",
+			metrics: Default().Metrics,
+		},
+		{
+			name:    "all interfaces",
+			metrics: Metrics{Enabled: true, Addr: ":9090", OpenTicketsInterval: Default().Metrics.OpenTicketsInterval},
+		},
+		{
+			name:    "disabled without addr",
+			metrics: Metrics{},
+		},
+		{
+			name:    "no addr",
+			metrics: Metrics{Enabled: true, OpenTicketsInterval: Default().Metrics.OpenTicketsInterval},
+			wantErr: "metrics.addr must be a host:port",
+		},
+		{
+			name:    "no port",
+			metrics: Metrics{Enabled: true, Addr: "127.0.0.1", OpenTicketsInterval: Default().Metrics.OpenTicketsInterval},
+			wantErr: "metrics.addr must be a host:port",
+		},
+		{
+			name:    "api port",
+			metrics: Metrics{Enabled: true, Addr: "0.0.0.0:8089", OpenTicketsInterval: Default().Metrics.OpenTicketsInterval},
+			wantErr: "metrics.addr must not use the port of the API",
+		},
+		{
+			name:    "no interval",
+			metrics: Metrics{Enabled: true, Addr: "127.0.0.1:9090"},
+			wantErr: "metrics.openTicketsInterval must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Metrics = tt.metrics
+
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	cfg, err := Load("test", []string{
+		"-db-host", "localhost",
+		"-db-name", "helpdesk",
+		"-auth-disabled=true",
+		"-metrics-addr", "10.0.0.1:9100",
+	})
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if cfg.Metrics.Addr != "10.0.0.1:9100" {
+		t.Errorf("Metrics.Addr = %q, want 10.0.0.1:9100", cfg.Metrics.Addr)
+	}
+}
+
+func TestHTTPIPExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		xff     string
+		want    string
+	}{
+		{name: "direct", remote: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "direct ignores xff", remote: "203.0.113.7:5000", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "direct ignores xff from loopback", remote: "127.0.0.1:5000", xff: "198.51.100.1", want: "127.0.0.1"},
+		{name: "trusted proxy", proxies: []string{"10.0.0.0/8"}, remote: "10.1.2.3:5000", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "trusted proxy address", proxies: []string{"10.1.2.3"}, remote: "10.1.2.3:5000", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "untrusted peer", proxies: []string{"10.0.0.0/8"}, remote: "203.0.113.7:5000", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "private peer is not trusted by default", proxies: []string{"10.0.0.0/8"}, remote: "192.168.1.1:5000", xff: "198.51.100.1", want: "192.168.1.1"},
+		{
+			name:    "spoofed xff behind proxy",
+			proxies: []string{"10.0.0.0/8"},
+			remote:  "10.1.2.3:5000",
+			xff:     "1.1.1.1, 198.51.100.1",
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "chain of proxies",
+			proxies: []string{"10.0.0.0/8"},
+			remote:  "10.1.2.3:5000",
+			xff:     "198.51.100.1, 10.9.9.9",
+			want:    "198.51.100.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HTTP{TrustedProxies: tt.proxies}
+			extract, err := h.IPExtractor()
+			if err != nil {
+				t.Fatalf("IPExtractor() = %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, tt.xff)
+			}
+			if got := extract(req); got != tt.want {
+				t.Errorf("client IP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, p := range []string{"proxy", "10.0.0.0/33", ""} {
+		h := &HTTP{TrustedProxies: []string{p}}
+		if _, err := h.IPExtractor(); err == nil {
+			t.Errorf("IPExtractor() with %q = nil error", p)
+		}
+	}
+}
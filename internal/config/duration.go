@@ -0,0 +1,29 @@
+// Package config loads the configuration of the API and holds the types
+// shared by the configuration of its features.
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Duration is a time.Duration read from a string such as "90s" in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvFile names the environment variable of the config file.
+const EnvFile = "CONFIG_FILE"
+
+// Load returns the configuration built from the defaults, the JSON file
+// named by the -config flag or CONFIG_FILE, the environment and the flags
+// in args, each overriding the previous ones.
+func Load(name string, args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fs.String("config", os.Getenv(EnvFile), "JSON config file")
+	flags := make(map[string]reflect.Value)
+	bindFlags(fs, reflect.ValueOf(cfg).Elem(), flags)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		b, err := os.ReadFile(*file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if v, ok := flags[f.Name]; ok && err == nil {
+			if e := setValue(v, f.Value.String()); e != nil {
+				err = fmt.Errorf("flag -%s: %w", f.Name, e)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// fields calls fn for each tagged field of the struct v, recursively.
+func fields(v reflect.Value, fn func(f reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(Duration(0)) {
+			fields(fv, fn)
+			continue
+		}
+		fn(f, fv)
+	}
+}
+
+func bindFlags(fs *flag.FlagSet, v reflect.Value, flags map[string]reflect.Value) {
+	fields(v, func(f reflect.StructField, fv reflect.Value) {
+		name := f.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		// Flags are parsed as strings and applied after the file and the
+		// environment, so only the flags given on the command line apply.
+		fs.String(name, "", f.Tag.Get("usage"))
+		flags[name] = fv
+	})
+}
+
+func applyEnv(v reflect.Value) error {
+	var err error
+	fields(v, func(f reflect.StructField, fv reflect.Value) {
+		name := f.Tag.Get("env")
+		if name == "" || err != nil {
+			return
+		}
+		if s, ok := os.LookupEnv(name); ok {
+			if e := setValue(fv, s); e != nil {
+				err = fmt.Errorf("%s: %w", name, e)
+			}
+		}
+	})
+	return err
+}
+
+// setValue parses s into the field v.
+func setValue(v reflect.Value, s string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(s)
+
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+
+	case float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(Duration(d)))
+
+	case []string:
+		var list []string
+		for _, e := range strings.Split(s, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				list = append(list, e)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with its secrets replaced, fit to
+// be printed or logged.
+func (c *Config) Redacted() *Config {
+	r := *c
+	fields(reflect.ValueOf(&r).Elem(), func(f reflect.StructField, fv reflect.Value) {
+		if f.Tag.Get("secret") == "true" && fv.Kind() == reflect.String && fv.String() != "" {
+			fv.SetString("REDACTED")
+		}
+	})
+	return &r
+}
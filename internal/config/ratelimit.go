@@ -0,0 +1,83 @@
+package config
+
+import "fmt"
+
+// Limit is a token bucket: Rate requests per second on average and up to
+// Burst at once. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// RateLimits configures rate limits.
+type RateLimits struct {
+	// IP limits every request by client IP before authentication. Behind
+	// a reverse proxy, set http.trustedProxies: otherwise every client
+	// shares the IP of the proxy, and X-Forwarded-For is not trusted.
+	IP Limit `json:"ip"`
+
+	// Groups limit the routes of a group, e.g. exports, per authenticated
+	// identity, or per client IP for anonymous requests.
+	Groups map[string]Limit `json:"groups"`
+
+	// Identities override the limits of groups for some identities, keyed
+	// by user:<user ID> or key:<API key ID>, then by group.
+	Identities map[string]map[string]Limit `json:"identities"`
+}
+
+// DefaultRateLimits returns the default limits of the route groups of
+// package ratelimit.
+func DefaultRateLimits() *RateLimits {
+	return &RateLimits{
+		IP: Limit{Rate: 50, Burst: 100},
+		Groups: map[string]Limit{
+			"tickets": {Rate: 10, Burst: 30},
+			"reports": {Rate: 2, Burst: 10},
+			"exports": {Rate: 0.2, Burst: 5},
+			"admin":   {Rate: 5, Burst: 10},
+		},
+	}
+}
+
+// Validate checks the limits and fills in defaults. Groups missing from the
+// config keep their default limits.
+func (c *RateLimits) Validate() error {
+	check := func(name string, l *Limit) error {
+		if l.Rate < 0 || l.Burst < 0 {
+			return fmt.Errorf("%s: rate and burst must not be negative", name)
+		}
+		if l.Rate > 0 && l.Burst == 0 {
+			l.Burst = max(1, int(l.Rate))
+		}
+		return nil
+	}
+
+	if c.Groups == nil {
+		c.Groups = make(map[string]Limit)
+	}
+	for g, l := range DefaultRateLimits().Groups {
+		if _, ok := c.Groups[g]; !ok {
+			c.Groups[g] = l
+		}
+	}
+
+	if err := check("ip", &c.IP); err != nil {
+		return err
+	}
+	for g, l := range c.Groups {
+		if err := check("group "+g, &l); err != nil {
+			return err
+		}
+		c.Groups[g] = l
+	}
+	for id, groups := range c.Identities {
+		for g, l := range groups {
+			if err := check("identity "+id+" group "+g, &l); err != nil {
+				return err
+			}
+			groups[g] = l
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package cors configures the origins allowed to call the API from a browser.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	stdmw "github.com/labstack/echo/v4/middleware"
+)
+
+// Profiles.
+const (
+	ProfileDev  = "dev"
+	ProfileProd = "prod"
+)
+
+// Config configures cross-origin requests.
+type Config struct {
+	// Origins are allowed origins such as https://dashboard.example.com.
+	// https://*.example.com allows any subdomain of example.com over https.
+	// "*" allows any origin, but not with credentials.
+	Origins []string `json:"origins"`
+
+	Methods       []string `json:"methods"`
+	Headers       []string `json:"headers"`
+	ExposeHeaders []string `json:"exposeHeaders"`
+
+	// Credentials allows cookies and Authorization headers.
+	Credentials bool `json:"credentials"`
+
+	// MaxAge is how many seconds browsers may cache a preflight response.
+	MaxAge int `json:"maxAge"`
+}
+
+// Profile returns the defaults of a profile. The dev profile allows local
+// front ends on any port; the prod profile allows no origin until some are
+// configured.
+func Profile(name string) (*Config, error) {
+	cfg := &Config{
+		Methods: []string{
+			http.MethodHead,
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodDelete,
+			http.MethodOptions,
+		},
+		Headers: []string{
+			echo.HeaderAuthorization,
+			echo.HeaderContentType,
+			"Accept-Language",
+			"If-None-Match",
+			echo.HeaderXRequestID,
+			"X-API-Key",
+		},
+		ExposeHeaders: []string{
+			echo.HeaderContentDisposition,
+			"ETag",
+			echo.HeaderRetryAfter,
+			echo.HeaderXRequestID,
+			"RateLimit-Limit",
+			"RateLimit-Remaining",
+			"RateLimit-Reset",
+		},
+		Credentials: true,
+	}
+
+	switch name {
+	case ProfileDev:
+		cfg.Origins = []string{"http://localhost:*", "http://127.0.0.1:*"}
+		cfg.MaxAge = 600
+	case ProfileProd, "":
+		cfg.MaxAge = 86400
+	default:
+		return nil, fmt.Errorf("unknown cors profile %q", name)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the origins.
+func (c *Config) Validate() error {
+	for _, o := range c.Origins {
+		if o == "*" {
+			if c.Credentials {
+				return errors.New(`origin "*" cannot be combined with credentials`)
+			}
+			continue
+		}
+
+		u, err := url.Parse(strings.Replace(o, "*", "wildcard", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("origin %q must be a scheme and host such as https://example.com", o)
+		}
+		if strings.Count(o, "*") > 1 {
+			return fmt.Errorf("origin %q has more than one wildcard", o)
+		}
+		if strings.Contains(o, "*") && !strings.Contains(o, "://*.") && !strings.HasSuffix(o, ":*") {
+			return fmt.Errorf("origin %q must use the wildcard for a subdomain or a port", o)
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether origin is allowed.
+func (c *Config) Allowed(origin string) bool {
+	for _, o := range c.Origins {
+		if matchOrigin(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches origin against a pattern with at most one wildcard,
+// standing for a subdomain (https://*.example.com) or a port
+// (http://localhost:*).
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || strings.EqualFold(pattern, origin) {
+		return true
+	}
+
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok || len(origin) <= len(prefix)+len(suffix) {
+		return false
+	}
+	if !strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) ||
+		!strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+		return false
+	}
+
+	middle := origin[len(prefix) : len(origin)-len(suffix)]
+	if suffix == "" {
+		// A port.
+		for _, r := range middle {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	// A subdomain, possibly nested, but never another host.
+	return !strings.ContainsAny(middle, "/:@")
+}
+
+// Middleware returns the CORS middleware of the config.
+func (c *Config) Middleware() echo.MiddlewareFunc {
+	return stdmw.CORSWithConfig(stdmw.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			return c.Allowed(origin), nil
+		},
+		AllowMethods:     c.Methods,
+		AllowHeaders:     c.Headers,
+		ExposeHeaders:    c.ExposeHeaders,
+		AllowCredentials: c.Credentials,
+		MaxAge:           c.MaxAge,
+	})
+}
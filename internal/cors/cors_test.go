@@ -0,0 +1,91 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{pattern: "*", origin: "https://anything.example", want: true},
+		{pattern: "https://example.com", origin: "https://example.com", want: true},
+		{pattern: "https://example.com", origin: "https://EXAMPLE.com", want: true},
+		{pattern: "https://example.com", origin: "http://example.com", want: false},
+		{pattern: "https://example.com", origin: "https://example.com:8443", want: false},
+
+		{pattern: "https://*.example.com", origin: "https://app.example.com", want: true},
+		{pattern: "https://*.example.com", origin: "https://a.b.example.com", want: true},
+		{pattern: "https://*.example.com", origin: "https://App.Example.com", want: true},
+		{pattern: "https://*.example.com", origin: "https://example.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://.example.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://evilexample.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://example.com.evil.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://evil.com/.example.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://evil.com:1.example.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://user@evil.com.example.com", want: false},
+		{pattern: "https://*.example.com", origin: "http://app.example.com", want: false},
+
+		{pattern: "http://localhost:*", origin: "http://localhost:3000", want: true},
+		{pattern: "http://localhost:*", origin: "http://localhost:", want: false},
+		{pattern: "http://localhost:*", origin: "http://localhost", want: false},
+		{pattern: "http://localhost:*", origin: "http://localhost:3000.evil.com", want: false},
+		{pattern: "http://localhost:*", origin: "http://localhost:abc", want: false},
+		{pattern: "http://localhost:*", origin: "https://localhost:3000", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.origin, func(t *testing.T) {
+			if got := matchOrigin(tt.pattern, tt.origin); got != tt.want {
+				t.Errorf("matchOrigin(%q, %q) = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreflightMethods(t *testing.T) {
+	for _, profile := range []string{ProfileDev, ProfileProd} {
+		t.Run(profile, func(t *testing.T) {
+			cfg, err := Profile(profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Origins = []string{"https://dashboard.example.com"}
+
+			e := echo.New()
+			e.Use(cfg.Middleware())
+			e.PUT("/v1/admin/log-level", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodOptions, "/v1/admin/log-level", nil)
+			req.Header.Set(echo.HeaderOrigin, "https://dashboard.example.com")
+			req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPut)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			allowed := strings.Split(rec.Header().Get(echo.HeaderAccessControlAllowMethods), ",")
+			for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+				if !containsTrimmed(allowed, m) {
+					t.Errorf("%s = %v, want %s", echo.HeaderAccessControlAllowMethods, allowed, m)
+				}
+			}
+		})
+	}
+}
+
+func containsTrimmed(list []string, s string) bool {
+	for _, e := range list {
+		if strings.TrimSpace(e) == s {
+			return true
+		}
+	}
+	return false
+}
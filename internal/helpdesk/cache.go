@@ -0,0 +1,190 @@
+package helpdesk
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxCachedReports bounds the number of queries the report cache holds.
+const maxCachedReports = 512
+
+// CacheStats are the counters of the report cache.
+type CacheStats struct {
+	Enabled bool          `json:"enabled"`
+	TTL     time.Duration `json:"ttl"`
+	Entries int           `json:"entries"`
+
+	// Hits counts the requests served from the cache, Misses those that
+	// queried the database and Shared those that waited for an identical
+	// request in flight.
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Shared    uint64 `json:"shared"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type cachedReport struct {
+	report  *summaryReport
+	expires time.Time
+}
+
+// reportCache holds the summary reports of recent queries. Identical
+// concurrent queries share one set of queries even if ttl is not positive,
+// in which case reports are not kept.
+type reportCache struct {
+	ttl   time.Duration
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]cachedReport
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	shared    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+func newReportCache(ttl time.Duration) *reportCache {
+	return &reportCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedReport),
+	}
+}
+
+// get returns the report cached under key, or calls query to fill the
+// cache. Concurrent calls for the same key share a single query, which
+// is not cancelled when only some of the callers go away.
+func (c *reportCache) get(ctx context.Context, key string, query func(context.Context) (*summaryReport, error)) (*summaryReport, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		c.hits.Add(1)
+		metrics.ObserveReportCache("hit")
+		return e.report, nil
+	}
+
+	var led bool
+	ch := c.group.DoChan(key, func() (any, error) {
+		led = true
+		c.misses.Add(1)
+		metrics.ObserveReportCache("miss")
+		r, err := query(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, r)
+		return r, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		if !led {
+			c.shared.Add(1)
+			metrics.ObserveReportCache("shared")
+		}
+		return res.Val.(*summaryReport), nil
+	}
+}
+
+func (c *reportCache) put(key string, r *summaryReport) {
+	if c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var evicted int
+	if len(c.entries) >= maxCachedReports {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+				evicted++
+			}
+		}
+	}
+	// Still full of live entries: make room at random.
+	for k := range c.entries {
+		if len(c.entries) < maxCachedReports {
+			break
+		}
+		delete(c.entries, k)
+		evicted++
+	}
+	c.evictions.Add(uint64(evicted))
+	metrics.AddReportCacheEvictions(evicted)
+
+	c.entries[key] = cachedReport{report: r, expires: now.Add(c.ttl)}
+	metrics.SetReportCacheEntries(len(c.entries))
+}
+
+// purge removes every entry and returns how many there were.
+func (c *reportCache) purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = make(map[string]cachedReport)
+	metrics.SetReportCacheEntries(0)
+	return n
+}
+
+// ReportCacheStats returns the counters of the report cache.
+func (s *Service) ReportCacheStats() CacheStats {
+	c := s.reports
+
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return CacheStats{
+		Enabled:   c.ttl > 0,
+		TTL:       c.ttl,
+		Entries:   entries,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Shared:    c.shared.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+// PurgeReportCache removes every cached report and returns how many there
+// were, so the next requests read the database again.
+func (s *Service) PurgeReportCache() int {
+	return s.reports.purge()
+}
+
+// cacheKey identifies the reports of a resolved query. Ranges given in
+// different forms share a key as long as they cover the same instants in
+// the same zone for the same scope.
+func (q *ReportQuery) cacheKey() string {
+	var b strings.Builder
+	b.WriteString(q.scope.String())
+	b.WriteByte('|')
+	for _, t := range []time.Time{q.from, q.until} {
+		if !t.IsZero() {
+			b.WriteString(t.UTC().Format(time.RFC3339Nano))
+		}
+		b.WriteByte('|')
+	}
+	if q.loc != nil {
+		b.WriteString(q.loc.String())
+	}
+	return b.String()
+}
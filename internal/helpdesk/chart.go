@@ -0,0 +1,105 @@
+package helpdesk
+
+import (
+	"fmt"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/language"
+)
+
+// chartRows is the number of rows a summary chart covers. Summary tables are
+// spaced at least this far apart so the charts next to them do not overlap.
+const chartRows = 18
+
+var chartDimension = excelize.ChartDimension{
+	Width:  640,
+	Height: 320,
+}
+
+// cellRange returns an absolute reference to the cells from col:startRow to
+// col:endRow in sheetName, e.g. 'Summary'!$A$5:$A$9.
+func cellRange(sheetName, col string, startRow, endRow int) string {
+	return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", sheetName, col, startRow, col, endRow)
+}
+
+// cellRef returns an absolute reference to a single cell in sheetName.
+func cellRef(sheetName, col string, row int) string {
+	return fmt.Sprintf("'%s'!$%s$%d", sheetName, col, row)
+}
+
+// categoryChart stacks the status columns of the category table whose header
+// is at headerRow and which has n rows, excluding the grand total.
+func categoryChart(lang language.Tag, sheetName string, headerRow, n int) *excelize.Chart {
+	series := make([]excelize.ChartSeries, 0, 3)
+	for _, col := range []string{"B", "C", "D"} {
+		series = append(series, excelize.ChartSeries{
+			Name:       cellRef(sheetName, col, headerRow),
+			Categories: cellRange(sheetName, "A", headerRow+1, headerRow+n),
+			Values:     cellRange(sheetName, col, headerRow+1, headerRow+n),
+		})
+	}
+
+	return &excelize.Chart{
+		Type:      excelize.BarStacked,
+		Series:    series,
+		Title:     []excelize.RichTextRun{{Text: i18n.T(lang, "Tickets by Type and Status")}},
+		Legend:    excelize.ChartLegend{Position: "bottom"},
+		Dimension: chartDimension,
+	}
+}
+
+// supporterChart plots the grand total of each supporter in the supporter
+// table whose header is at headerRow and which has n rows.
+func supporterChart(lang language.Tag, sheetName string, headerRow, n int) *excelize.Chart {
+	return &excelize.Chart{
+		Type: excelize.Bar,
+		Series: []excelize.ChartSeries{
+			{
+				Name:       cellRef(sheetName, "E", headerRow),
+				Categories: cellRange(sheetName, "A", headerRow+1, headerRow+n),
+				Values:     cellRange(sheetName, "E", headerRow+1, headerRow+n),
+			},
+		},
+		Title:     []excelize.RichTextRun{{Text: i18n.T(lang, "IT Technical Workload")}},
+		Legend:    excelize.ChartLegend{Position: "none"},
+		Dimension: chartDimension,
+	}
+}
+
+// priorityChart shows the share of each priority using the grand total row
+// of the priority table whose header is at headerRow and which has n rows.
+func priorityChart(lang language.Tag, sheetName string, headerRow, n int) *excelize.Chart {
+	totalRow := headerRow + n + 1
+	return &excelize.Chart{
+		Type: excelize.Pie,
+		Series: []excelize.ChartSeries{
+			{
+				Name:       cellRef(sheetName, "A", totalRow),
+				Categories: fmt.Sprintf("'%s'!$B$%d:$E$%d", sheetName, headerRow, headerRow),
+				Values:     fmt.Sprintf("'%s'!$B$%d:$E$%d", sheetName, totalRow, totalRow),
+			},
+		},
+		Title:     []excelize.RichTextRun{{Text: i18n.T(lang, "Priority Distribution")}},
+		Legend:    excelize.ChartLegend{Position: "right"},
+		Dimension: chartDimension,
+	}
+}
+
+// monthlyChart plots the ticket volume per month from the monthly table whose
+// header is at headerRow and which has n rows.
+func monthlyChart(lang language.Tag, sheetName string, headerRow, n int) *excelize.Chart {
+	return &excelize.Chart{
+		Type: excelize.Line,
+		Series: []excelize.ChartSeries{
+			{
+				Name:       cellRef(sheetName, "B", headerRow),
+				Categories: cellRange(sheetName, "A", headerRow+1, headerRow+n),
+				Values:     cellRange(sheetName, "B", headerRow+1, headerRow+n),
+			},
+		},
+		Title:     []excelize.RichTextRun{{Text: i18n.T(lang, "Monthly Ticket Volume")}},
+		Legend:    excelize.ChartLegend{Position: "none"},
+		Dimension: chartDimension,
+	}
+}
@@ -0,0 +1,97 @@
+package helpdesk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/audit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/logging"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// utf8BOM lets Excel detect that the CSV is UTF-8 so Lao text is readable.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+func (s *Service) GenCSV(ctx context.Context, in *BatchGetTicketsQuery) (_ *bytes.Buffer, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GenCSV")
+	defer func() { endSpan(span, err) }()
+
+	zlog := logging.With(ctx, s.zlog).With(
+		zap.String("method", "GenCSV"),
+		zap.Any("query", in),
+	)
+
+	zlog.Info("starting to gen csv")
+
+	lang := i18n.FromContext(ctx)
+
+	if err := in.resolve(time.Now(), s.loc); err != nil {
+		return nil, err
+	}
+	in.scope = scopeOf(ctx, zlog)
+
+	tmpl, err := s.exportTemplate(in.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(utf8BOM)
+	w := csv.NewWriter(buf)
+
+	record := make([]string, len(tmpl.Columns))
+	for i, c := range tmpl.Columns {
+		record[i] = i18n.T(lang, c.Label)
+	}
+	if err := w.Write(record); err != nil {
+		zlog.Error("failed to write csv header", zap.Error(err))
+		return nil, err
+	}
+
+	var nextID string
+	var n int
+	for {
+		tickets, err := batchGetTickets(ctx, s.db, ticketsPerBatch, nextID, in)
+		if err != nil {
+			zlog.Error("failed to batch get tickets", zap.Error(err))
+			return nil, err
+		}
+
+		if len(tickets) == 0 {
+			break
+		}
+		nextID = tickets[len(tickets)-1].ID
+		audit.AddRows(ctx, len(tickets))
+		n += len(tickets)
+
+		for _, t := range tickets {
+			for i, c := range tmpl.Columns {
+				switch v := c.exportValue(t, lang).(type) {
+				case nil:
+					record[i] = ""
+				case time.Time:
+					record[i] = v.Format(c.dateFormat())
+				default:
+					record[i] = fmt.Sprint(v)
+				}
+			}
+			if err := w.Write(record); err != nil {
+				zlog.Error("failed to write csv record", zap.Error(err))
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		zlog.Error("failed to flush csv", zap.Error(err))
+		return nil, err
+	}
+	metrics.ObserveExport("csv", n, buf.Len())
+
+	return buf, nil
+}
@@ -0,0 +1,248 @@
+package helpdesk
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const (
+	layoutDate          = "2006-01-02"
+	layoutDateTimeLocal = "2006-01-02T15:04:05"
+)
+
+// DateTime is a timestamp given in a request.
+// It accepts an RFC 3339 timestamp, a date (2006-01-02) or a date and time
+// without an offset (2006-01-02T15:04:05). Values without an offset are
+// resolved in the time zone of the request.
+type DateTime struct {
+	t      time.Time
+	layout string
+}
+
+// ParseDateTime parses s as a DateTime.
+func ParseDateTime(s string) (DateTime, error) {
+	for _, layout := range []string{time.RFC3339Nano, layoutDateTimeLocal, layoutDate} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return DateTime{t: t, layout: layout}, nil
+		}
+	}
+
+	st, _ := status.New(codes.InvalidArgument, "Date must be a date (YYYY-MM-DD) or an RFC 3339 timestamp.").
+		WithDetails(&edpb.ErrorInfo{
+			Reason:   "INVALID_DATE",
+			Domain:   "helpdesk",
+			Metadata: map[string]string{"value": s},
+		})
+	return DateTime{}, st.Err()
+}
+
+// IsZero reports whether d is unset.
+func (d DateTime) IsZero() bool {
+	return d.t.IsZero()
+}
+
+// IsDate reports whether d was given as a date without a time of day.
+func (d DateTime) IsDate() bool {
+	return d.layout == layoutDate
+}
+
+// In returns the instant d represents when read in loc.
+func (d DateTime) In(loc *time.Location) time.Time {
+	if d.layout == time.RFC3339Nano {
+		return d.t.In(loc)
+	}
+	return time.Date(d.t.Year(), d.t.Month(), d.t.Day(), d.t.Hour(), d.t.Minute(), d.t.Second(), d.t.Nanosecond(), loc)
+}
+
+// UnmarshalParam implements echo.BindUnmarshaler.
+func (d *DateTime) UnmarshalParam(src string) error {
+	if src == "" {
+		*d = DateTime{}
+		return nil
+	}
+
+	v, err := ParseDateTime(src)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *DateTime) UnmarshalText(b []byte) error {
+	return d.UnmarshalParam(string(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *DateTime) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		*d = DateTime{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.UnmarshalParam(s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d DateTime) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(d.t.Format(d.layout)), nil
+}
+
+// Relative date ranges accepted by DateRange.Range.
+const (
+	RangeToday       = "today"
+	RangeLast7Days   = "last7d"
+	RangeThisMonth   = "thisMonth"
+	RangeLastMonth   = "lastMonth"
+	RangeThisQuarter = "thisQuarter"
+	RangeYearToDate  = "ytd"
+)
+
+// DateRange filters tickets by their creation time.
+// CreatedBefore is inclusive: a date covers the whole day.
+// Range selects a relative range instead of CreatedAfter and CreatedBefore.
+// All dates are interpreted in TimeZone.
+type DateRange struct {
+	CreatedBefore DateTime `json:"createdBefore" query:"createdBefore"`
+	CreatedAfter  DateTime `json:"createdAfter" query:"createdAfter"`
+	Range         string   `json:"range" query:"range"`
+	TimeZone      string   `json:"timeZone" query:"timeZone"`
+
+	// loc is the zone of the request and dbLoc the zone the database
+	// records its timestamps in.
+	loc   *time.Location
+	dbLoc *time.Location
+
+	// from is inclusive and until is exclusive.
+	from  time.Time
+	until time.Time
+}
+
+// resolve computes the bounds of the range at now.
+func (r *DateRange) resolve(now time.Time, dbLoc *time.Location) error {
+	loc, err := resolveLocation(r.TimeZone, dbLoc)
+	if err != nil {
+		return err
+	}
+	r.loc, r.dbLoc = loc, dbLoc
+
+	if r.Range != "" {
+		if !r.CreatedAfter.IsZero() || !r.CreatedBefore.IsZero() {
+			return rangeViolation("Range cannot be combined with createdAfter or createdBefore.")
+		}
+
+		from, until, ok := relativeRange(r.Range, now.In(loc))
+		if !ok {
+			return rangeViolation("Range must be one of today, last7d, thisMonth, lastMonth, thisQuarter or ytd.")
+		}
+		r.from, r.until = from, until
+		return nil
+	}
+
+	r.from, r.until = time.Time{}, time.Time{}
+	if !r.CreatedAfter.IsZero() {
+		r.from = r.CreatedAfter.In(loc)
+	}
+	if !r.CreatedBefore.IsZero() {
+		r.until = r.CreatedBefore.In(loc)
+		if r.CreatedBefore.IsDate() {
+			r.until = r.until.AddDate(0, 0, 1)
+		} else {
+			r.until = r.until.Add(time.Nanosecond)
+		}
+	}
+
+	return nil
+}
+
+// Bounds returns the first and last instant covered by the range in the
+// zone of the request. A bound is zero if the range is open on that side.
+func (r *DateRange) Bounds() (first, last time.Time) {
+	if !r.until.IsZero() {
+		last = r.until.Add(-time.Nanosecond)
+	}
+	return r.from, last
+}
+
+// contains reports whether t is within the range.
+func (r *DateRange) contains(t time.Time) bool {
+	if !r.from.IsZero() && t.Before(r.from) {
+		return false
+	}
+	if !r.until.IsZero() && !t.Before(r.until) {
+		return false
+	}
+	return true
+}
+
+// predicates returns the conditions on created_at selecting the range.
+func (r *DateRange) predicates() []sq.Sqlizer {
+	preds := make([]sq.Sqlizer, 0, 2)
+	if !r.from.IsZero() {
+		preds = append(preds, sq.GtOrEq{"created_at": dbTime(r.from, r.dbLoc)})
+	}
+	if !r.until.IsZero() {
+		preds = append(preds, sq.Lt{"created_at": dbTime(r.until, r.dbLoc)})
+	}
+
+	return preds
+}
+
+// relativeRange returns the bounds of the named range relative to now.
+func relativeRange(name string, now time.Time) (from, until time.Time, ok bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch name {
+	case RangeToday:
+		return today, tomorrow, true
+
+	case RangeLast7Days:
+		return today.AddDate(0, 0, -6), tomorrow, true
+
+	case RangeThisMonth:
+		return month, month.AddDate(0, 1, 0), true
+
+	case RangeLastMonth:
+		return month.AddDate(0, -1, 0), month, true
+
+	case RangeThisQuarter:
+		quarter := month.AddDate(0, -int(now.Month()-1)%3, 0)
+		return quarter, quarter.AddDate(0, 3, 0), true
+
+	case RangeYearToDate:
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location()), tomorrow, true
+
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+}
+
+func rangeViolation(msg string) error {
+	s, _ := status.New(codes.InvalidArgument, msg).
+		WithDetails(&edpb.BadRequest{
+			FieldViolations: []*edpb.BadRequest_FieldViolation{
+				{
+					Field:       "range",
+					Description: msg,
+				},
+			},
+		})
+	return s.Err()
+}
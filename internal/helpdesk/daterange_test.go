@@ -0,0 +1,256 @@
+package helpdesk
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testLoc = time.FixedZone("ICT", 7*60*60)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, testLoc)
+}
+
+func TestRelativeRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantFrom  time.Time
+		wantUntil time.Time
+		wantOK    bool
+	}{
+		{
+			name:      RangeToday,
+			now:       time.Date(2024, 6, 15, 23, 59, 0, 0, testLoc),
+			wantFrom:  date(2024, 6, 15),
+			wantUntil: date(2024, 6, 16),
+			wantOK:    true,
+		},
+		{
+			name:      RangeLast7Days,
+			now:       time.Date(2024, 3, 3, 9, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 2, 26),
+			wantUntil: date(2024, 3, 4),
+			wantOK:    true,
+		},
+		{
+			name:      RangeThisMonth,
+			now:       time.Date(2024, 2, 29, 12, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 2, 1),
+			wantUntil: date(2024, 3, 1),
+			wantOK:    true,
+		},
+		{
+			name:      RangeLastMonth,
+			now:       time.Date(2024, 1, 10, 12, 0, 0, 0, testLoc),
+			wantFrom:  date(2023, 12, 1),
+			wantUntil: date(2024, 1, 1),
+			wantOK:    true,
+		},
+		{
+			name:      RangeLastMonth,
+			now:       time.Date(2024, 3, 31, 12, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 2, 1),
+			wantUntil: date(2024, 3, 1),
+			wantOK:    true,
+		},
+		{
+			name:      RangeThisQuarter,
+			now:       time.Date(2024, 1, 1, 0, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 1, 1),
+			wantUntil: date(2024, 4, 1),
+			wantOK:    true,
+		},
+		{
+			name:      RangeThisQuarter,
+			now:       time.Date(2024, 8, 20, 12, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 7, 1),
+			wantUntil: date(2024, 10, 1),
+			wantOK:    true,
+		},
+		{
+			name:      RangeThisQuarter,
+			now:       time.Date(2024, 12, 31, 23, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 10, 1),
+			wantUntil: date(2025, 1, 1),
+			wantOK:    true,
+		},
+		{
+			name:      RangeYearToDate,
+			now:       time.Date(2024, 5, 5, 12, 0, 0, 0, testLoc),
+			wantFrom:  date(2024, 1, 1),
+			wantUntil: date(2024, 5, 6),
+			wantOK:    true,
+		},
+		{
+			name: "lastYear",
+			now:  time.Date(2024, 5, 5, 12, 0, 0, 0, testLoc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.now.Format(layoutDate), func(t *testing.T) {
+			from, until, ok := relativeRange(tt.name, tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("relativeRange() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !from.Equal(tt.wantFrom) || !until.Equal(tt.wantUntil) {
+				t.Errorf("relativeRange() = [%v, %v), want [%v, %v)", from, until, tt.wantFrom, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantErr  bool
+		wantDate bool
+		want     time.Time
+	}{
+		{
+			in:       "2024-06-15",
+			wantDate: true,
+			want:     date(2024, 6, 15),
+		},
+		{
+			in:   "2024-06-15T08:30:00",
+			want: time.Date(2024, 6, 15, 8, 30, 0, 0, testLoc),
+		},
+		{
+			in:   "2024-06-15T08:30:00Z",
+			want: time.Date(2024, 6, 15, 15, 30, 0, 0, testLoc),
+		},
+		{
+			in:   "2024-06-15T08:30:00.5+07:00",
+			want: time.Date(2024, 6, 15, 8, 30, 0, 5e8, testLoc),
+		},
+		{in: "15/06/2024", wantErr: true},
+		{in: "2024-02-30", wantErr: true},
+		{in: "2024-06-15 08:30:00", wantErr: true},
+		{in: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			d, err := ParseDateTime(tt.in)
+			if tt.wantErr {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("ParseDateTime() error = %v, want InvalidArgument", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDateTime() error = %v", err)
+			}
+
+			if d.IsDate() != tt.wantDate {
+				t.Errorf("IsDate() = %v, want %v", d.IsDate(), tt.wantDate)
+			}
+			if got := d.In(testLoc); !got.Equal(tt.want) {
+				t.Errorf("In() = %v, want %v", got, tt.want)
+			}
+			if b, _ := d.MarshalText(); string(b) != tt.in {
+				t.Errorf("MarshalText() = %s, want %s", b, tt.in)
+			}
+		})
+	}
+}
+
+func TestDateTimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantErr  bool
+		wantZero bool
+	}{
+		{in: `"2024-06-15"`},
+		{in: `null`, wantZero: true},
+		{in: `""`, wantZero: true},
+		{in: `20240615`, wantErr: true},
+		{in: `"June 15"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var d DateTime
+			err := d.UnmarshalJSON([]byte(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && d.IsZero() != tt.wantZero {
+				t.Errorf("IsZero() = %v, want %v", d.IsZero(), tt.wantZero)
+			}
+		})
+	}
+}
+
+func TestDateRangeResolve(t *testing.T) {
+	now := time.Date(2024, 6, 15, 10, 0, 0, 0, testLoc)
+	mustParse := func(s string) DateTime {
+		d, err := ParseDateTime(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		r         DateRange
+		wantFrom  time.Time
+		wantUntil time.Time
+		wantErr   bool
+	}{
+		{
+			name: "open",
+		},
+		{
+			name:      "dates include the last day",
+			r:         DateRange{CreatedAfter: mustParse("2024-06-01"), CreatedBefore: mustParse("2024-06-10")},
+			wantFrom:  date(2024, 6, 1),
+			wantUntil: date(2024, 6, 11),
+		},
+		{
+			name:      "times include the last instant",
+			r:         DateRange{CreatedBefore: mustParse("2024-06-10T12:00:00")},
+			wantUntil: time.Date(2024, 6, 10, 12, 0, 0, 1, testLoc),
+		},
+		{
+			name:      "relative range",
+			r:         DateRange{Range: RangeThisMonth},
+			wantFrom:  date(2024, 6, 1),
+			wantUntil: date(2024, 7, 1),
+		},
+		{
+			name:    "range with dates",
+			r:       DateRange{Range: RangeToday, CreatedAfter: mustParse("2024-06-01")},
+			wantErr: true,
+		},
+		{
+			name:    "unknown range",
+			r:       DateRange{Range: "forever"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.r.resolve(now, testLoc)
+			if tt.wantErr {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("resolve() error = %v, want InvalidArgument", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if !tt.r.from.Equal(tt.wantFrom) || !tt.r.until.Equal(tt.wantUntil) {
+				t.Errorf("resolve() = [%v, %v), want [%v, %v)", tt.r.from, tt.r.until, tt.wantFrom, tt.wantUntil)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package helpdesk
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// errorDomain is the domain of the ErrorInfo of the errors of the service.
+const errorDomain = "helpdesk"
+
+// Error is an error of the service a client can act on. It converts to a
+// gRPC status that holds Message but not the cause, which may reveal the
+// queries or the database.
+type Error struct {
+	Code    codes.Code
+	Reason  string
+	Message string
+
+	// Field is the request field at fault, if any.
+	Field string
+
+	// RetryAfter is how long the client should wait before retrying, if
+	// the call may succeed then.
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the status of e, with an ErrorInfo and, if set, a
+// BadRequest of Field and a RetryInfo of RetryAfter.
+func (e *Error) GRPCStatus() *status.Status {
+	details := []protoadapt.MessageV1{
+		&edpb.ErrorInfo{
+			Reason: e.Reason,
+			Domain: errorDomain,
+		},
+	}
+	if e.Field != "" {
+		details = append(details, &edpb.BadRequest{
+			FieldViolations: []*edpb.BadRequest_FieldViolation{
+				{
+					Field:       e.Field,
+					Description: e.Message,
+				},
+			},
+		})
+	}
+	if e.RetryAfter > 0 {
+		details = append(details, &edpb.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+
+	s := status.New(e.Code, e.Message)
+	if ds, err := s.WithDetails(details...); err == nil {
+		return ds
+	}
+	return s
+}
+
+// ErrTicketNotFound is returned if no ticket matches the query.
+var ErrTicketNotFound = &Error{
+	Code:    codes.NotFound,
+	Reason:  "TICKET_NOT_FOUND",
+	Message: "Ticket does not exist.",
+}
+
+// errInvalidPageToken returns the error of a page token that is not one
+// returned by the service.
+func errInvalidPageToken(err error) error {
+	return &Error{
+		Code:    codes.InvalidArgument,
+		Reason:  "INVALID_PAGE_TOKEN",
+		Message: "Page token is not valid.",
+		Field:   "pageToken",
+		Err:     err,
+	}
+}
+
+// unavailableRetryAfter is the delay suggested to clients when the
+// database is unavailable.
+const unavailableRetryAfter = 5 * time.Second
+
+// Numbers of SQL Server errors that pass if the query is retried.
+var transientSQLErrors = map[int32]bool{
+	4060:  true, // cannot open database
+	40197: true, // service error processing the request
+	40501: true, // service busy
+	40613: true, // database unavailable
+	49918: true, // not enough resources
+	49919: true, // too many operations
+	49920: true, // too many operations
+}
+
+// sqlDeadlock is the number of the error of a query chosen as the victim
+// of a deadlock.
+const sqlDeadlock = 1205
+
+// dbError classifies err, an error of executing a query, as a timeout, an
+// outage of the database or an internal error. msg describes the failed
+// step in the logs.
+func dbError(msg string, err error) error {
+	e := &Error{
+		Code:    codes.Internal,
+		Reason:  "DB_ERROR",
+		Message: "An internal error occurred",
+		Err:     fmt.Errorf("%s: %w", msg, err),
+	}
+
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code, e.Reason, e.Message = codes.DeadlineExceeded, "QUERY_TIMEOUT", "The query took too long."
+
+	case errors.Is(err, context.Canceled):
+		e.Code, e.Reason, e.Message = codes.Canceled, "CANCELED", "The request was canceled."
+
+	case errors.As(err, &sqlErr) && sqlErr.SQLErrorNumber() == sqlDeadlock:
+		e.Code, e.Reason, e.Message = codes.Aborted, "QUERY_ABORTED", "The query was aborted, try again."
+		e.RetryAfter = time.Second
+
+	case errors.As(err, &sqlErr) && transientSQLErrors[sqlErr.SQLErrorNumber()],
+		errors.As(err, &netErr),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		e.Code, e.Reason, e.Message = codes.Unavailable, "DB_UNAVAILABLE", "Database is unavailable."
+		e.RetryAfter = unavailableRetryAfter
+	}
+
+	return e
+}
@@ -4,50 +4,137 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"sync"
+	"sort"
 	"time"
 
+	"github.com/10664kls/helpdesk-dashboad-api/internal/audit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/logging"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/metrics"
 	"github.com/xuri/excelize/v2"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	"golang.org/x/text/language"
 )
 
-func (s *Service) GenExcel(ctx context.Context, in *BatchGetTicketsQuery) (*bytes.Buffer, error) {
-	zlog := s.zlog.With(
+func (s *Service) GenExcel(ctx context.Context, in *BatchGetTicketsQuery) (_ *bytes.Buffer, err error) {
+	ctx, span := tracer.Start(ctx, "Service.GenExcel")
+	defer func() { endSpan(span, err) }()
+
+	zlog := logging.With(ctx, s.zlog).With(
 		zap.String("method", "GenExcel"),
 		zap.Any("query", in),
 	)
 
 	zlog.Info("starting to gen excel")
 
-	categoryReports, err := listCategoryReports(ctx, s.db, &ReportQuery{
-		CreatedBefore: in.CreatedBefore,
-		CreatedAfter:  in.CreatedAfter,
-	})
+	lang := i18n.FromContext(ctx)
+
+	if err := in.resolve(time.Now(), s.loc); err != nil {
+		return nil, err
+	}
+	in.scope = scopeOf(ctx, zlog)
+
+	tmpl, err := s.exportTemplate(in.Template)
 	if err != nil {
-		zlog.Error("failed to list category reports", zap.Error(err))
 		return nil, err
 	}
 
-	supporterReports, err := listSupporterReports(ctx, s.db, &ReportQuery{
-		CreatedBefore: in.CreatedBefore,
-		CreatedAfter:  in.CreatedAfter,
-	})
+	rq := &ReportQuery{
+		DateRange: in.DateRange,
+		scope:     in.scope,
+	}
+
+	rctx, phase := tracer.Start(ctx, "GenExcel.reports")
+	summary, err := s.listSummaryReports(rctx, zlog, rq)
+	endSpan(phase, err)
 	if err != nil {
-		zlog.Error("failed to list supporter reports", zap.Error(err))
 		return nil, err
 	}
 
-	priorityReports, err := listPriorityReports(ctx, s.db, &ReportQuery{
-		CreatedBefore: in.CreatedBefore,
-		CreatedAfter:  in.CreatedAfter,
-	})
+	from, to := in.period()
+	wb, err := newExcelWorkbook(lang, tmpl, summary, from, to)
+	if err != nil {
+		zlog.Error("failed to create workbook", zap.Error(err))
+		return nil, err
+	}
+	defer wb.fx.Close()
+
+	tctx, phase := tracer.Start(ctx, "GenExcel.tickets")
+	var nextID string
+	for {
+		tickets, err := batchGetTickets(tctx, s.db, ticketsPerBatch, nextID, in)
+		if err != nil {
+			endSpan(phase, err)
+			zlog.Error("failed to batch get statements", zap.Error(err))
+			return nil, err
+		}
+
+		if len(tickets) == 0 {
+			break
+		}
+
+		s.mu.Lock()
+		nextID = tickets[len(tickets)-1].ID
+		s.mu.Unlock()
+
+		wb.addTickets(tickets)
+		audit.AddRows(ctx, len(tickets))
+	}
+	phase.SetAttributes(attribute.Int("tickets", wb.tickets()))
+	phase.End()
+
+	_, phase = tracer.Start(ctx, "GenExcel.format")
+	err = wb.finish()
+	endSpan(phase, err)
 	if err != nil {
-		zlog.Error("failed to list priority reports", zap.Error(err))
+		zlog.Error("failed to format workbook", zap.Error(err))
 		return nil, err
 	}
 
+	_, phase = tracer.Start(ctx, "GenExcel.write")
+	buf, err := wb.fx.WriteToBuffer()
+	endSpan(phase, err)
+	if err != nil {
+		zlog.Error("failed to write file to buffer", zap.Error(err))
+		return nil, err
+	}
+	metrics.ObserveExport("excel", wb.tickets(), buf.Len())
+
+	return buf, nil
+}
+
+// excelWorkbook is the workbook of an export: the tickets on the first
+// sheet and the summary reports with their charts on the second. Cells are
+// written one after another, so identical exports produce identical files.
+type excelWorkbook struct {
+	fx      *excelize.File
+	lang    language.Tag
+	tmpl    *ExportTemplate
+	summary *summaryReport
+	widths  *columnWidths
+
+	sheetTicket  string
+	sheetSummary string
+
+	startCategoryReportRow  int
+	startSupporterReportRow int
+	startPriorityReportRow  int
+	startMonthlyReportRow   int
+
+	// nextRow is the row of the next ticket.
+	nextRow int
+}
+
+// newExcelWorkbook creates the workbook with the ticket header and the
+// summary reports of the period from to.
+func newExcelWorkbook(lang language.Tag, tmpl *ExportTemplate, summary *summaryReport, from, to string) (_ *excelWorkbook, err error) {
 	fx := excelize.NewFile()
-	defer fx.Close()
+	defer func() {
+		if err != nil {
+			fx.Close()
+		}
+	}()
 
 	styleHeader, err := fx.NewStyle(&excelize.Style{
 		Font: &excelize.Font{
@@ -63,116 +150,159 @@ func (s *Service) GenExcel(ctx context.Context, in *BatchGetTicketsQuery) (*byte
 		},
 	})
 	if err != nil {
-		zlog.Error("failed to create style", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("failed to create style: %w", err)
 	}
 
-	const sheetTicket = "Help Desk Requests"
+	sheetTicket := i18n.T(lang, "Help Desk Requests")
 	fx.SetSheetName("Sheet1", sheetTicket)
 
+	widths, err := formatTicketSheet(fx, sheetTicket, tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format ticket sheet: %w", err)
+	}
+
 	// add header
-	fx.SetCellValue(sheetTicket, "A1", "HelpDesk Number")
-	fx.SetCellValue(sheetTicket, "B1", "Type of Form")
-	fx.SetCellValue(sheetTicket, "C1", "Title")
-	fx.SetCellValue(sheetTicket, "D1", "Description")
-	fx.SetCellValue(sheetTicket, "E1", "Request Date")
-	fx.SetCellValue(sheetTicket, "F1", "ID Staff Request")
-	fx.SetCellValue(sheetTicket, "G1", "Request by (Eng)")
-	fx.SetCellValue(sheetTicket, "H1", "Position")
-	fx.SetCellValue(sheetTicket, "I1", "Department")
-	fx.SetCellValue(sheetTicket, "J1", "Branch")
-	fx.SetCellValue(sheetTicket, "K1", "IT Support Name")
-	fx.SetCellValue(sheetTicket, "L1", "IT Support Position")
-	fx.SetCellValue(sheetTicket, "M1", "Priority")
-	fx.SetCellValue(sheetTicket, "N1", "Status")
-	fx.SetCellValue(sheetTicket, "O1", "Closed Date")
+	for i, c := range tmpl.Columns {
+		label := i18n.T(lang, c.Label)
+		fx.SetCellValue(sheetTicket, cellName(i+1, 1), label)
+		widths.observe(i+1, label)
+	}
 	fx.SetRowStyle(sheetTicket, 1, 1, styleHeader)
 
 	// Summary sheet
-	const sheetSummary = "Summary"
+	sheetSummary := i18n.T(lang, "Summary")
 	if _, err := fx.NewSheet(sheetSummary); err != nil {
-		zlog.Error("failed to create sheet summary", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("failed to create sheet summary: %w", err)
 	}
 
-	var from, to string
-	to = time.Now().Format("02/01/2006")
-	from = in.CreatedAfter.Format("02/01/2006")
-	if !in.CreatedBefore.IsZero() {
-		to = in.CreatedBefore.Format("02/01/2006")
-	}
-	fx.SetCellValue(sheetSummary, "A1", fmt.Sprintf(`Date update: %s-%s`, from, to))
+	fx.SetCellValue(sheetSummary, "A1", i18n.Tf(lang, "Date update: %s-%s", from, to))
 	fx.MergeCell(sheetSummary, "A1", "D1")
 	fx.SetRowStyle(sheetSummary, 1, 1, styleHeader)
 
-	var wg sync.WaitGroup
+	categoryReports := summary.categories
+	supporterReports := summary.supporters
+	priorityReports := summary.priorities
+	monthlyReports := summary.months
+
 	const startCategoryReportRow = 4
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startCategoryReportRow), "Helpdesk Ticket Summary Report Type")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startCategoryReportRow), "In Progress")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("C%d", startCategoryReportRow), "Resolved")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("D%d", startCategoryReportRow), "Blank")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("E%d", startCategoryReportRow), "Grand Total")
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startCategoryReportRow), i18n.T(lang, "Helpdesk Ticket Summary Report Type"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startCategoryReportRow), i18n.T(lang, "In Progress"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("C%d", startCategoryReportRow), i18n.T(lang, "Resolved"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("D%d", startCategoryReportRow), i18n.T(lang, "Blank"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("E%d", startCategoryReportRow), i18n.T(lang, "Grand Total"))
 	fx.SetRowStyle(sheetSummary, startCategoryReportRow, startCategoryReportRow, styleHeader)
-	wg.Add(1)
-	go genCategoryReportToExcel(fx, &wg, sheetSummary, startCategoryReportRow, styleHeader, categoryReports)
-
-	startSupporterReportRow := 10 + startCategoryReportRow + len(categoryReports)
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startSupporterReportRow), "IT Technical Summary Report Full Name")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startSupporterReportRow), "In Progress")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("C%d", startSupporterReportRow), "Resolved")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("D%d", startSupporterReportRow), "Blank")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("E%d", startSupporterReportRow), "Grand Total")
+	genCategoryReportToExcel(fx, lang, sheetSummary, startCategoryReportRow, styleHeader, categoryReports)
+
+	startSupporterReportRow := startCategoryReportRow + max(10+len(categoryReports), chartRows)
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startSupporterReportRow), i18n.T(lang, "IT Technical Summary Report Full Name"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startSupporterReportRow), i18n.T(lang, "In Progress"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("C%d", startSupporterReportRow), i18n.T(lang, "Resolved"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("D%d", startSupporterReportRow), i18n.T(lang, "Blank"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("E%d", startSupporterReportRow), i18n.T(lang, "Grand Total"))
 	fx.SetRowStyle(sheetSummary, startSupporterReportRow, startSupporterReportRow, styleHeader)
-	wg.Add(1)
-	go genSupporterReportToExcel(fx, &wg, sheetSummary, startSupporterReportRow, styleHeader, supporterReports)
-
-	startPriorityReportRow := 10 + startSupporterReportRow + len(supporterReports)
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startPriorityReportRow), "Priority Summary Report Type")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startPriorityReportRow), "High")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("C%d", startPriorityReportRow), "Medium")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("D%d", startPriorityReportRow), "Low")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("E%d", startPriorityReportRow), "Blank")
-	fx.SetCellValue(sheetSummary, fmt.Sprintf("F%d", startPriorityReportRow), "Grand Total")
+	genSupporterReportToExcel(fx, lang, sheetSummary, startSupporterReportRow, styleHeader, supporterReports)
+
+	startPriorityReportRow := startSupporterReportRow + max(10+len(supporterReports), chartRows)
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startPriorityReportRow), i18n.T(lang, "Priority Summary Report Type"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startPriorityReportRow), i18n.T(lang, "High"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("C%d", startPriorityReportRow), i18n.T(lang, "Medium"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("D%d", startPriorityReportRow), i18n.T(lang, "Low"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("E%d", startPriorityReportRow), i18n.T(lang, "Blank"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("F%d", startPriorityReportRow), i18n.T(lang, "Grand Total"))
 	fx.SetRowStyle(sheetSummary, startPriorityReportRow, startPriorityReportRow, styleHeader)
-	wg.Add(1)
-	go genPriorityReportToExcel(fx, &wg, sheetSummary, startPriorityReportRow, styleHeader, priorityReports)
-
-	startTicketsRow := 2
-	var nextID string
-	for {
-		tickets, err := batchGetTickets(ctx, s.db, 200, nextID, in)
-		if err != nil {
-			zlog.Error("failed to batch get statements", zap.Error(err))
-			return nil, err
-		}
-
-		if len(tickets) == 0 {
-			break
-		}
+	genPriorityReportToExcel(fx, lang, sheetSummary, startPriorityReportRow, styleHeader, priorityReports)
+
+	startMonthlyReportRow := startPriorityReportRow + max(10+len(priorityReports), chartRows)
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("A%d", startMonthlyReportRow), i18n.T(lang, "Monthly Ticket Volume"))
+	fx.SetCellValue(sheetSummary, fmt.Sprintf("B%d", startMonthlyReportRow), i18n.T(lang, "Grand Total"))
+	fx.SetRowStyle(sheetSummary, startMonthlyReportRow, startMonthlyReportRow, styleHeader)
+	genMonthlyReportToExcel(fx, lang, sheetSummary, startMonthlyReportRow, styleHeader, monthlyReports)
+
+	return &excelWorkbook{
+		fx:      fx,
+		lang:    lang,
+		tmpl:    tmpl,
+		summary: summary,
+		widths:  widths,
+
+		sheetTicket:  sheetTicket,
+		sheetSummary: sheetSummary,
+
+		startCategoryReportRow:  startCategoryReportRow,
+		startSupporterReportRow: startSupporterReportRow,
+		startPriorityReportRow:  startPriorityReportRow,
+		startMonthlyReportRow:   startMonthlyReportRow,
+
+		nextRow: 2,
+	}, nil
+}
 
-		s.mu.Lock()
-		nextID = tickets[len(tickets)-1].ID
-		s.mu.Unlock()
+// addTickets writes tickets below the ones already added.
+func (w *excelWorkbook) addTickets(tickets []*Ticket) {
+	genTicketsToExcel(w.fx, w.lang, w.sheetTicket, w.nextRow, w.tmpl, w.widths, tickets)
+	w.nextRow += len(tickets)
+}
 
-		wg.Add(1)
-		go genTicketsToExcel(fx, &wg, sheetTicket, startTicketsRow, tickets)
+// tickets returns the number of tickets added.
+func (w *excelWorkbook) tickets() int {
+	return w.nextRow - 2
+}
 
-		startTicketsRow += len(tickets)
+// finish formats the ticket sheet and adds the charts of the summary once
+// all tickets are added.
+func (w *excelWorkbook) finish() error {
+	if err := finishTicketSheet(w.fx, w.lang, w.sheetTicket, w.tmpl, w.nextRow-1, w.widths); err != nil {
+		return fmt.Errorf("failed to finish ticket sheet: %w", err)
 	}
 
-	wg.Wait()
+	lang, sheetSummary := w.lang, w.sheetSummary
+	charts := []struct {
+		cell  string
+		rows  int
+		chart *excelize.Chart
+	}{
+		{
+			cell:  fmt.Sprintf("H%d", w.startCategoryReportRow),
+			rows:  len(w.summary.categories),
+			chart: categoryChart(lang, sheetSummary, w.startCategoryReportRow, len(w.summary.categories)),
+		},
+		{
+			cell:  fmt.Sprintf("H%d", w.startSupporterReportRow),
+			rows:  len(w.summary.supporters),
+			chart: supporterChart(lang, sheetSummary, w.startSupporterReportRow, len(w.summary.supporters)),
+		},
+		{
+			cell:  fmt.Sprintf("H%d", w.startPriorityReportRow),
+			rows:  len(w.summary.priorities),
+			chart: priorityChart(lang, sheetSummary, w.startPriorityReportRow, len(w.summary.priorities)),
+		},
+		{
+			cell:  fmt.Sprintf("H%d", w.startMonthlyReportRow),
+			rows:  len(w.summary.months),
+			chart: monthlyChart(lang, sheetSummary, w.startMonthlyReportRow, len(w.summary.months)),
+		},
+	}
+	for _, c := range charts {
+		if c.rows == 0 {
+			continue
+		}
+		if err := w.fx.AddChart(sheetSummary, c.cell, c.chart); err != nil {
+			return fmt.Errorf("failed to add chart: %w", err)
+		}
+	}
 
-	buf, err := fx.WriteToBuffer()
-	if err != nil {
-		zlog.Error("failed to write file to buffer", zap.Error(err))
-		return nil, err
+	// The first drawing registers the image extensions in map order; sort
+	// them to keep the workbook identical across exports.
+	if ct := w.fx.ContentTypes; ct != nil {
+		sort.SliceStable(ct.Defaults, func(i, j int) bool {
+			return ct.Defaults[i].Extension < ct.Defaults[j].Extension
+		})
 	}
 
-	return buf, nil
+	return nil
 }
 
-func genSupporterReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName string, startRow, style int, supporters []*SupporterReport) {
-	defer wg.Done()
+func genSupporterReportToExcel(fx *excelize.File, lang language.Tag, sheetName string, startRow, style int, supporters []*SupporterReport) {
 	sum := make(map[string]int64, 0)
 	sum["inProgress"] = 0
 	sum["resolved"] = 0
@@ -180,7 +310,7 @@ func genSupporterReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName
 	sum["total"] = 0
 
 	for i, r := range supporters {
-		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), r.Name)
+		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), reportName(lang, r.Name))
 		fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+i+1), r.InProgress)
 		fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+i+1), r.Resolved)
 		fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+i+1), r.Blank)
@@ -192,7 +322,7 @@ func genSupporterReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName
 		sum["total"] += r.Total
 	}
 
-	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(supporters)+1), "Grand Total")
+	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(supporters)+1), i18n.T(lang, "Grand Total"))
 	fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+len(supporters)+1), sum["inProgress"])
 	fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+len(supporters)+1), sum["resolved"])
 	fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+len(supporters)+1), sum["blank"])
@@ -201,16 +331,14 @@ func genSupporterReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName
 	fx.SetRowStyle(sheetName, startRow+len(supporters)+1, startRow+len(supporters)+1, style)
 }
 
-func genCategoryReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName string, startRow, style int, categories []*CategoryReport) {
-	defer wg.Done()
-
+func genCategoryReportToExcel(fx *excelize.File, lang language.Tag, sheetName string, startRow, style int, categories []*CategoryReport) {
 	sum := make(map[string]int64, 0)
 	sum["inProgress"] = 0
 	sum["resolved"] = 0
 	sum["blank"] = 0
 	sum["total"] = 0
 	for i, r := range categories {
-		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), r.Name)
+		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), reportName(lang, r.Name))
 		fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+i+1), r.InProgress)
 		fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+i+1), r.Resolved)
 		fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+i+1), r.Blank)
@@ -222,7 +350,7 @@ func genCategoryReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName s
 		sum["total"] += r.Total
 	}
 
-	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(categories)+1), "Grand Total")
+	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(categories)+1), i18n.T(lang, "Grand Total"))
 	fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+len(categories)+1), sum["inProgress"])
 	fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+len(categories)+1), sum["resolved"])
 	fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+len(categories)+1), sum["blank"])
@@ -231,9 +359,7 @@ func genCategoryReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName s
 	fx.SetRowStyle(sheetName, startRow+len(categories)+1, startRow+len(categories)+1, style)
 }
 
-func genPriorityReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName string, startRow, style int, priorities []*PriorityReport) {
-	defer wg.Done()
-
+func genPriorityReportToExcel(fx *excelize.File, lang language.Tag, sheetName string, startRow, style int, priorities []*PriorityReport) {
 	sum := make(map[string]int64, 0)
 	sum["high"] = 0
 	sum["medium"] = 0
@@ -241,7 +367,7 @@ func genPriorityReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName s
 	sum["blank"] = 0
 	sum["total"] = 0
 	for i, r := range priorities {
-		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), r.Name)
+		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), reportName(lang, r.Name))
 		fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+i+1), r.High)
 		fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+i+1), r.Medium)
 		fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+i+1), r.Low)
@@ -255,7 +381,7 @@ func genPriorityReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName s
 		sum["total"] += r.Total
 	}
 
-	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(priorities)+1), "Grand Total")
+	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(priorities)+1), i18n.T(lang, "Grand Total"))
 	fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+len(priorities)+1), sum["high"])
 	fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+len(priorities)+1), sum["medium"])
 	fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+len(priorities)+1), sum["low"])
@@ -265,27 +391,41 @@ func genPriorityReportToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName s
 	fx.SetRowStyle(sheetName, startRow+len(priorities)+1, startRow+len(priorities)+1, style)
 }
 
-func genTicketsToExcel(fx *excelize.File, wg *sync.WaitGroup, sheetName string, startRow int, tickets []*Ticket) {
-	defer wg.Done()
+func genTicketsToExcel(fx *excelize.File, lang language.Tag, sheetName string, startRow int, tmpl *ExportTemplate, widths *columnWidths, tickets []*Ticket) {
 	for i, s := range tickets {
-		var closedDate string
-		if s.ClosedDate.Format("2006-01-02") != "1900-01-01" {
-			closedDate = s.ClosedDate.Format("02/01/2006")
+		for j, c := range tmpl.Columns {
+			switch v := c.exportValue(s, lang).(type) {
+			case nil:
+			case time.Time:
+				fx.SetCellValue(sheetName, cellName(j+1, startRow+i), excelTime(v))
+				widths.observe(j+1, v.Format(c.dateFormat()))
+			default:
+				fx.SetCellValue(sheetName, cellName(j+1, startRow+i), v)
+				widths.observe(j+1, fmt.Sprint(v))
+			}
 		}
-		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i), s.Number)
-		fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+i), s.Category)
-		fx.SetCellValue(sheetName, fmt.Sprintf("C%d", startRow+i), s.Title)
-		fx.SetCellValue(sheetName, fmt.Sprintf("D%d", startRow+i), s.Description)
-		fx.SetCellValue(sheetName, fmt.Sprintf("E%d", startRow+i), s.CreatedAt.Format("02/01/2006 15:04:05"))
-		fx.SetCellValue(sheetName, fmt.Sprintf("F%d", startRow+i), s.Employee.ID)
-		fx.SetCellValue(sheetName, fmt.Sprintf("G%d", startRow+i), s.Employee.DisplayName)
-		fx.SetCellValue(sheetName, fmt.Sprintf("H%d", startRow+i), s.Employee.Position)
-		fx.SetCellValue(sheetName, fmt.Sprintf("I%d", startRow+i), s.Employee.Department)
-		fx.SetCellValue(sheetName, fmt.Sprintf("J%d", startRow+i), s.Employee.Branch)
-		fx.SetCellValue(sheetName, fmt.Sprintf("K%d", startRow+i), s.Supporter.DisplayName)
-		fx.SetCellValue(sheetName, fmt.Sprintf("L%d", startRow+i), s.Supporter.Position)
-		fx.SetCellValue(sheetName, fmt.Sprintf("M%d", startRow+i), s.Priority)
-		fx.SetCellValue(sheetName, fmt.Sprintf("N%d", startRow+i), s.Status)
-		fx.SetCellValue(sheetName, fmt.Sprintf("O%d", startRow+i), closedDate)
 	}
 }
+
+func genMonthlyReportToExcel(fx *excelize.File, lang language.Tag, sheetName string, startRow, style int, months []*MonthlyReport) {
+	var total int64
+	for i, r := range months {
+		fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+i+1), r.Month)
+		fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+i+1), r.Total)
+
+		total += r.Total
+	}
+
+	fx.SetCellValue(sheetName, fmt.Sprintf("A%d", startRow+len(months)+1), i18n.T(lang, "Grand Total"))
+	fx.SetCellValue(sheetName, fmt.Sprintf("B%d", startRow+len(months)+1), total)
+
+	fx.SetRowStyle(sheetName, startRow+len(months)+1, startRow+len(months)+1, style)
+}
+
+// reportName translates the name of a report row if it is blank.
+func reportName(lang language.Tag, name string) string {
+	if name == blankName {
+		return i18n.T(lang, name)
+	}
+	return name
+}
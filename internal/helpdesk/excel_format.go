@@ -0,0 +1,184 @@
+package helpdesk
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/language"
+)
+
+const (
+	wrapColWidth = 60
+	minColWidth  = 10
+	maxColWidth  = 50
+)
+
+// priorityHighlights maps priorities, as stored in the view, to the fill
+// color of the cells holding them.
+var priorityHighlights = map[string]string{
+	"HIGH":    "F8CBAD",
+	"HIGHT":   "F8CBAD",
+	"MEDIUM":  "FFE699",
+	"MEDIUEM": "FFE699",
+	"LOW":     "C6EFCE",
+}
+
+// statusHighlights maps statuses to the fill color of the cells holding them.
+var statusHighlights = map[string]string{
+	"RESOLVED":    "C6EFCE",
+	"IN_PROGRESS": "BDD7EE",
+	"PENDING":     "FFE699",
+	"RE_PENDING":  "FFE699",
+	"SENDING":     "DDEBF7",
+	"REQUEST":     "EDEDED",
+	"REJECTED":    "F8CBAD",
+	"CANCELED":    "D9D9D9",
+}
+
+// columnWidths tracks the widest value written to each column of a sheet.
+type columnWidths struct {
+	widths map[int]int
+}
+
+func (w *columnWidths) observe(col int, s string) {
+	n := utf8.RuneCountInString(s)
+	if n > w.widths[col] {
+		w.widths[col] = n
+	}
+}
+
+func (w *columnWidths) width(col int) float64 {
+	return min(max(float64(w.widths[col])*1.2+2, minColWidth), maxColWidth)
+}
+
+// formatTicketSheet sets the column formats of the ticket sheet. It must be
+// called before any row is written so new cells pick up the column styles.
+func formatTicketSheet(fx *excelize.File, sheetName string, tmpl *ExportTemplate) (*columnWidths, error) {
+	for i, c := range tmpl.Columns {
+		var style excelize.Style
+		if layout := c.dateFormat(); layout != "" {
+			numFmt := goLayoutToNumFmt(layout)
+			style.CustomNumFmt = &numFmt
+		}
+		if ticketFields[c.Field].wrap {
+			style.Alignment = &excelize.Alignment{
+				WrapText: true,
+				Vertical: "top",
+			}
+		}
+		if style.CustomNumFmt == nil && style.Alignment == nil {
+			continue
+		}
+
+		styleID, err := fx.NewStyle(&style)
+		if err != nil {
+			return nil, err
+		}
+
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		if err := fx.SetColStyle(sheetName, name, styleID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &columnWidths{widths: make(map[int]int)}, nil
+}
+
+// finishTicketSheet sizes the columns of the ticket sheet, freezes its header
+// and adds an autofilter and highlights over the rows up to lastRow.
+func finishTicketSheet(fx *excelize.File, lang language.Tag, sheetName string, tmpl *ExportTemplate, lastRow int, widths *columnWidths) error {
+	for i, c := range tmpl.Columns {
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		width := widths.width(i + 1)
+		if ticketFields[c.Field].wrap {
+			width = wrapColWidth
+		}
+		if err := fx.SetColWidth(sheetName, name, name, width); err != nil {
+			return err
+		}
+	}
+
+	err := fx.SetPanes(sheetName, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return err
+	}
+
+	if lastRow < 2 {
+		return nil
+	}
+
+	if err := fx.AutoFilter(sheetName, fmt.Sprintf("A1:%s", cellName(len(tmpl.Columns), lastRow)), nil); err != nil {
+		return err
+	}
+
+	for i, c := range tmpl.Columns {
+		colors := ticketFields[c.Field].highlights
+		if len(colors) == 0 {
+			continue
+		}
+
+		rangeRef := fmt.Sprintf("%s:%s", cellName(i+1, 2), cellName(i+1, lastRow))
+		// Styles are added in a fixed order to keep the workbook identical
+		// across exports.
+		values := make([]string, 0, len(colors))
+		for value := range colors {
+			values = append(values, value)
+		}
+		slices.Sort(values)
+
+		opts := make([]excelize.ConditionalFormatOptions, 0, len(colors))
+		for _, value := range values {
+			color := colors[value]
+			style, err := fx.NewConditionalStyle(&excelize.Style{
+				Fill: excelize.Fill{
+					Type:    "pattern",
+					Color:   []string{color},
+					Pattern: 1,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			opts = append(opts, excelize.ConditionalFormatOptions{
+				Type:     "cell",
+				Criteria: "==",
+				Format:   &style,
+				Value:    formulaString(i18n.T(lang, value)),
+			})
+		}
+		if err := fx.SetConditionalFormat(sheetName, rangeRef, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formulaString quotes s as a string of an Excel formula, in which quotes
+// are doubled rather than escaped.
+func formulaString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// cellName returns the name of the cell at col and row, e.g. A1.
+func cellName(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
+
+// excelTime returns t with its wall clock in UTC.
+// Excel dates have no zone, and excelize converts instants to UTC first.
+func excelTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
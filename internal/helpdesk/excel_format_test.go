@@ -0,0 +1,24 @@
+package helpdesk
+
+import "testing"
+
+func TestFormulaString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "RESOLVED", want: `"RESOLVED"`},
+		{s: "ແກ້ໄຂແລ້ວ", want: `"ແກ້ໄຂແລ້ວ"`},
+		{s: `say "hi"`, want: `"say ""hi"""`},
+		{s: `C:\path`, want: `"C:\path"`},
+		{s: "", want: `""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := formulaString(tt.s); got != tt.want {
+				t.Errorf("formulaString(%q) = %s, want %s", tt.s, got, tt.want)
+			}
+		})
+	}
+}
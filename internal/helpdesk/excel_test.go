@@ -0,0 +1,113 @@
+package helpdesk
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+func testSummaryReport() *summaryReport {
+	return &summaryReport{
+		categories: []*CategoryReport{
+			{Name: "Hardware", InProgress: 2, Resolved: 5, Total: 7},
+			{Name: blankName, Blank: 1, Total: 1},
+		},
+		supporters: []*SupporterReport{
+			{Name: "Somchai", InProgress: 1, Resolved: 3, Total: 4},
+			{Name: "Vilay", InProgress: 1, Resolved: 2, Blank: 1, Total: 4},
+		},
+		priorities: []*PriorityReport{
+			{Name: "Hardware", High: 3, Medium: 2, Low: 2, Total: 7},
+		},
+		months: []*MonthlyReport{
+			{Month: "2024-05", Total: 3},
+			{Month: "2024-06", Total: 5},
+		},
+	}
+}
+
+func testTickets(n int) []*Ticket {
+	statuses := []string{"RESOLVED", "IN_PROGRESS", "PENDING", "REJECTED"}
+	priorities := []string{"HIGH", "MEDIUM", "LOW"}
+
+	created := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+	tickets := make([]*Ticket, n)
+	for i := range tickets {
+		tickets[i] = &Ticket{
+			ID:          fmt.Sprintf("T%04d", i),
+			Number:      fmt.Sprintf("HD-%04d", i),
+			Category:    "Hardware",
+			Priority:    priorities[i%len(priorities)],
+			Status:      statuses[i%len(statuses)],
+			Title:       fmt.Sprintf("Ticket %d", i),
+			Description: "Printer does not print",
+			Employee: Employee{
+				ID:          fmt.Sprintf("E%03d", i%7),
+				DisplayName: fmt.Sprintf("Employee %d", i%7),
+				Department:  "IT",
+				Branch:      "Vientiane",
+			},
+			Supporter:  Supporter{DisplayName: "Somchai"},
+			CreatedAt:  created.Add(time.Duration(i) * time.Hour),
+			ClosedDate: created.Add(time.Duration(i+24) * time.Hour),
+		}
+	}
+	return tickets
+}
+
+// buildExcel builds the workbook of an export the way GenExcel does, adding
+// the tickets in batches of ticketsPerBatch.
+func buildExcel(t *testing.T, lang language.Tag, tickets []*Ticket) []byte {
+	t.Helper()
+
+	wb, err := newExcelWorkbook(lang, defaultExportTemplate(), testSummaryReport(), "01/05/2024", "30/06/2024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.fx.Close()
+
+	for start := 0; start < len(tickets); start += ticketsPerBatch {
+		wb.addTickets(tickets[start:min(start+ticketsPerBatch, len(tickets))])
+	}
+	if got := wb.tickets(); got != len(tickets) {
+		t.Fatalf("tickets() = %d, want %d", got, len(tickets))
+	}
+	if err := wb.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := wb.fx.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExcelWorkbookDeterministic(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    language.Tag
+		tickets int
+	}{
+		{name: "no tickets", lang: language.English, tickets: 0},
+		{name: "one batch", lang: language.English, tickets: 10},
+		{name: "several batches", lang: language.English, tickets: 2*ticketsPerBatch + 17},
+		{name: "lao", lang: language.Lao, tickets: ticketsPerBatch + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tickets := testTickets(tt.tickets)
+
+			first := sha256.Sum256(buildExcel(t, tt.lang, tickets))
+			for i := 0; i < 3; i++ {
+				if got := sha256.Sum256(buildExcel(t, tt.lang, tickets)); got != first {
+					t.Fatalf("export %d hashes to %x, want %x", i+2, got, first)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package helpdesk
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/metrics"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// closedStatuses are the statuses of the view a ticket does not leave.
+var closedStatuses = []string{
+	"FINISHED,MANAGER(APPROVE),IT(RESOLVE)",
+	"FINISHED,MANAGER(APPROVE),IT(CANCEL)",
+	"REJECT,MANAGER(REJECT)",
+}
+
+// CountOpenTickets returns the number of tickets neither resolved, canceled
+// nor rejected by PriorityLevel. Tickets without a priority are counted as
+// BLANK.
+func (s *Service) CountOpenTickets(ctx context.Context) (_ map[string]int64, err error) {
+	ctx, qs := startQuery(ctx, "countOpenTickets")
+	defer qs.end(&err)
+
+	q, args := sq.
+		Select("priority", "COUNT(*)").
+		From("v_hepldesk_ticket_report").
+		Where(sq.NotEq{"status": closedStatuses}).
+		GroupBy("priority").
+		PlaceholderFormat(sq.AtP).
+		MustSql()
+
+	qs.statement(q)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, dbError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var priority sql.NullString
+		var n int64
+		if err := rows.Scan(&priority, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		level := PriorityLevel(priority.String)
+		if level == "" {
+			level = "BLANK"
+		}
+		counts[level] += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("failed to iterate rows", err)
+	}
+
+	return counts, nil
+}
+
+// RecordOpenTickets updates the open tickets gauge every interval until ctx
+// is done.
+func (s *Service) RecordOpenTickets(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	zlog := s.zlog.With(zap.String("component", "metrics"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		counts, err := s.CountOpenTickets(ctx)
+		switch {
+		case err == nil:
+			metrics.SetOpenTickets(counts)
+		case ctx.Err() == nil:
+			zlog.Error("failed to count open tickets", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package helpdesk
+
+import (
+	"context"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// scope restricts a query to the tickets its caller may see.
+// The zero scope sees every ticket.
+type scope struct {
+	column string
+	value  string
+}
+
+// scopeOf returns the scope of the caller stored in ctx:
+//   - IT staff, admins and API keys see every ticket,
+//   - branch managers the tickets of their branch,
+//   - department managers the tickets of their department,
+//   - anyone else the tickets they created.
+//
+// Requests without an identity, such as scheduled reports or requests
+// served while authentication is disabled, see every ticket.
+func scopeOf(ctx context.Context, zlog *zap.Logger) scope {
+	id, ok := auth.FromContext(ctx)
+	if !ok {
+		return scope{}
+	}
+
+	switch {
+	case id.KeyID != "", id.HasRole(auth.RoleAdmin, auth.RoleIT):
+		return scope{}
+
+	case id.HasRole(auth.RoleBranchManager) && id.Branch != "":
+		return scope{column: "branch", value: id.Branch}
+
+	case id.HasRole(auth.RoleDepartmentManager) && id.Department != "":
+		return scope{column: "department", value: id.Department}
+	}
+
+	if id.HasRole(auth.RoleBranchManager, auth.RoleDepartmentManager) {
+		zlog.Warn("manager has no branch or department, restricting to own tickets", zap.String("userId", id.UserID))
+	}
+	return scope{column: "creator_number", value: id.UserID}
+}
+
+// predicates returns the condition selecting the tickets of the scope.
+func (s scope) predicates() []sq.Sqlizer {
+	if s.column == "" {
+		return nil
+	}
+	return []sq.Sqlizer{sq.Eq{s.column: s.value}}
+}
+
+// match reports whether t is within the scope.
+func (s scope) match(t *Ticket) bool {
+	switch s.column {
+	case "branch":
+		return t.Employee.Branch == s.value
+	case "department":
+		return t.Employee.Department == s.value
+	case "creator_number":
+		return t.Employee.ID == s.value
+	default:
+		return true
+	}
+}
+
+// String identifies the scope in cache keys.
+func (s scope) String() string {
+	if s.column == "" {
+		return "*"
+	}
+	return s.column + "=" + s.value
+}
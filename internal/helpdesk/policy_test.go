@@ -0,0 +1,110 @@
+package helpdesk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"go.uber.org/zap"
+)
+
+func TestScopeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		id   *auth.Identity
+		want scope
+	}{
+		{
+			name: "no identity",
+			want: scope{},
+		},
+		{
+			name: "api key",
+			id:   &auth.Identity{KeyID: "k1", Scopes: []string{"tickets:read"}},
+			want: scope{},
+		},
+		{
+			name: "admin",
+			id:   &auth.Identity{UserID: "1001", Branch: "HQ", Roles: []string{auth.RoleAdmin}},
+			want: scope{},
+		},
+		{
+			name: "it staff",
+			id:   &auth.Identity{UserID: "1002", Roles: []string{auth.RoleEmployee, auth.RoleIT}},
+			want: scope{},
+		},
+		{
+			name: "branch manager",
+			id:   &auth.Identity{UserID: "1003", Branch: "Pakse", Department: "Sales", Roles: []string{auth.RoleBranchManager}},
+			want: scope{column: "branch", value: "Pakse"},
+		},
+		{
+			name: "branch and department manager",
+			id:   &auth.Identity{UserID: "1004", Branch: "Pakse", Department: "Sales", Roles: []string{auth.RoleDepartmentManager, auth.RoleBranchManager}},
+			want: scope{column: "branch", value: "Pakse"},
+		},
+		{
+			name: "department manager",
+			id:   &auth.Identity{UserID: "1005", Branch: "Pakse", Department: "Sales", Roles: []string{auth.RoleDepartmentManager}},
+			want: scope{column: "department", value: "Sales"},
+		},
+		{
+			name: "branch manager without branch",
+			id:   &auth.Identity{UserID: "1006", Department: "Sales", Roles: []string{auth.RoleBranchManager}},
+			want: scope{column: "creator_number", value: "1006"},
+		},
+		{
+			name: "department manager without department",
+			id:   &auth.Identity{UserID: "1007", Roles: []string{auth.RoleDepartmentManager}},
+			want: scope{column: "creator_number", value: "1007"},
+		},
+		{
+			name: "employee",
+			id:   &auth.Identity{UserID: "1008", Branch: "HQ", Department: "HR", Roles: []string{auth.RoleEmployee}},
+			want: scope{column: "creator_number", value: "1008"},
+		},
+		{
+			name: "no roles",
+			id:   &auth.Identity{UserID: "1009"},
+			want: scope{column: "creator_number", value: "1009"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.id != nil {
+				ctx = auth.NewContext(ctx, tt.id)
+			}
+
+			if got := scopeOf(ctx, zap.NewNop()); got != tt.want {
+				t.Errorf("scopeOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeMatch(t *testing.T) {
+	ticket := &Ticket{Employee: Employee{ID: "1008", Department: "HR", Branch: "HQ"}}
+
+	tests := []struct {
+		scope scope
+		want  bool
+	}{
+		{scope: scope{}, want: true},
+		{scope: scope{column: "branch", value: "HQ"}, want: true},
+		{scope: scope{column: "branch", value: "Pakse"}, want: false},
+		{scope: scope{column: "department", value: "HR"}, want: true},
+		{scope: scope{column: "department", value: "Sales"}, want: false},
+		{scope: scope{column: "creator_number", value: "1008"}, want: true},
+		{scope: scope{column: "creator_number", value: "1009"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope.String(), func(t *testing.T) {
+			if got := tt.scope.match(ticket); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/10664kls/helpdesk-dashboad-api/internal/audit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/logging"
 	"github.com/10664kls/helpdesk-dashboad-api/internal/pager"
 	"go.uber.org/zap"
 )
@@ -15,9 +18,53 @@ type Service struct {
 	db   *sql.DB
 	zlog *zap.Logger
 	mu   *sync.Mutex
+	loc  *time.Location
+
+	templates map[string]*ExportTemplate
+	pdfFont   []byte
+	reports   *reportCache
+}
+
+// Option configures optional settings of the Service.
+type Option func(*Service)
+
+// WithLocation sets the time zone the database records its timestamps in.
+// It is also the default zone for requests that do not set a timeZone.
+// Defaults to DefaultTimeZone.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Service) {
+		s.loc = loc
+	}
+}
+
+// WithExportTemplates sets the templates exports can select by name.
+// A template named DefaultExportTemplate replaces the built-in default.
+func WithExportTemplates(templates map[string]*ExportTemplate) Option {
+	return func(s *Service) {
+		s.templates = templates
+	}
+}
+
+// WithPDFFont sets the TrueType font PDF reports are printed with.
+// The default Go font has no Lao glyphs, so a font such as Noto Sans Lao
+// must be set to print Lao text.
+func WithPDFFont(ttf []byte) Option {
+	return func(s *Service) {
+		s.pdfFont = ttf
+	}
+}
+
+// WithReportCache caches the reports of a query for ttl. Identical
+// concurrent report requests share one set of queries either way.
+// Reports are not kept once their queries finish if ttl is not positive,
+// which is the default.
+func WithReportCache(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.reports = newReportCache(ttl)
+	}
 }
 
-func NewService(_ context.Context, db *sql.DB, zlog *zap.Logger) (*Service, error) {
+func NewService(_ context.Context, db *sql.DB, zlog *zap.Logger, opts ...Option) (*Service, error) {
 	if db == nil {
 		return nil, errors.New("db is nil")
 	}
@@ -26,11 +73,52 @@ func NewService(_ context.Context, db *sql.DB, zlog *zap.Logger) (*Service, erro
 		return nil, errors.New("zlog is nil")
 	}
 
-	return &Service{
+	s := &Service{
 		db:   db,
 		zlog: zlog,
 		mu:   new(sync.Mutex),
-	}, nil
+
+		reports: newReportCache(0),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.loc == nil {
+		loc, err := time.LoadLocation(DefaultTimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default time zone: %w", err)
+		}
+		s.loc = loc
+	}
+
+	return s, nil
+}
+
+// Ready checks that the database answers. If checkView is set, it also
+// queries the ticket view the service reads.
+func (s *Service) Ready(ctx context.Context, checkView bool) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.Ready")
+	defer func() { endSpan(span, err) }()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping db: %w", err)
+	}
+
+	if checkView {
+		var id string
+		err := s.db.QueryRowContext(ctx, "SELECT TOP 1 id FROM v_hepldesk_ticket_report").Scan(&id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to query ticket view: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DBStats returns the statistics of the database connection pool.
+func (s *Service) DBStats() sql.DBStats {
+	return s.db.Stats()
 }
 
 type ListTicketsResult struct {
@@ -38,19 +126,28 @@ type ListTicketsResult struct {
 	NextPageToken string    `json:"nextPageToken"`
 }
 
-func (s *Service) ListTickets(ctx context.Context, in *TicketQuery) (*ListTicketsResult, error) {
-	zlog := s.zlog.With(
+func (s *Service) ListTickets(ctx context.Context, in *TicketQuery) (_ *ListTicketsResult, err error) {
+	ctx, span := tracer.Start(ctx, "Service.ListTickets")
+	defer func() { endSpan(span, err) }()
+
+	zlog := logging.With(ctx, s.zlog).With(
 		zap.String("method", "ListTickets"),
 		zap.Any("query", in),
 	)
 
-	zlog.Info("starting to list tickets")
+	zlog.Debug("starting to list tickets")
+
+	if err := in.resolve(time.Now(), s.loc); err != nil {
+		return nil, err
+	}
+	in.scope = scopeOf(ctx, zlog)
 
 	tickets, err := listTickets(ctx, s.db, in)
 	if err != nil {
 		zlog.Error("failed to list tickets", zap.Error(err))
 		return nil, err
 	}
+	audit.AddRows(ctx, len(tickets))
 
 	var pageToken string
 	if l := len(tickets); l > 0 && l == int(pager.Size(in.PageSize)) {
@@ -79,6 +176,10 @@ type Ticket struct {
 	Supporter   Supporter `json:"supporter"`
 	CreatedAt   time.Time `json:"createdAt"`
 	ClosedDate  time.Time `json:"closedDate"`
+
+	// rawStatus is the status as stored in the view, which the status
+	// filter of queries matches.
+	rawStatus string
 }
 
 type Employee struct {
@@ -5,32 +5,39 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/10664kls/helpdesk-dashboad-api/internal/pager"
 	sq "github.com/Masterminds/squirrel"
 )
 
-var ErrTicketNotFound = errors.New("ticket not found")
+// blankName is the name of report rows without a category or supporter.
+const blankName = "(Blank)"
 
 type TicketQuery struct {
-	ID            string    `json:"id" query:"id"`
-	Number        string    `json:"number" query:"number"`
-	Category      string    `json:"category" query:"category"`
-	Priority      string    `json:"priority" query:"priority"`
-	Status        string    `json:"status" query:"status"`
-	EmployeeID    string    `json:"employeeId" query:"employeeId"`
-	CreatedBefore time.Time `json:"createdBefore" query:"createdBefore"`
-	CreatedAfter  time.Time `json:"createdAfter" query:"createdAfter"`
-	PageSize      uint64    `json:"pageSize" query:"pageSize"`
-	PageToken     string    `json:"pageToken" query:"pageToken"`
+	ID         string `json:"id" query:"id"`
+	Number     string `json:"number" query:"number"`
+	Category   string `json:"category" query:"category"`
+	Priority   string `json:"priority" query:"priority"`
+	Status     string `json:"status" query:"status"`
+	EmployeeID string `json:"employeeId" query:"employeeId"`
+	PageSize   uint64 `json:"pageSize" query:"pageSize"`
+	PageToken  string `json:"pageToken" query:"pageToken"`
+	DateRange
+
+	scope scope
 }
 
 func (q *TicketQuery) ToSql() (string, []any, error) {
 	and := sq.And{}
 
+	if q.ID != "" {
+		and = append(and, sq.Eq{"id": q.ID})
+	}
+
 	if q.Status != "" {
-		and = append(and, sq.Expr("status LIKE %?%", q.Status))
+		and = append(and, statusLike(q.Status))
 	}
 
 	if q.Priority != "" {
@@ -48,17 +55,13 @@ func (q *TicketQuery) ToSql() (string, []any, error) {
 	if q.EmployeeID != "" {
 		and = append(and, sq.Eq{"creator_number": q.EmployeeID})
 	}
-	if !q.CreatedBefore.IsZero() {
-		and = append(and, sq.LtOrEq{"created_at": q.CreatedBefore})
-	}
-	if !q.CreatedAfter.IsZero() {
-		and = append(and, sq.GtOrEq{"created_at": q.CreatedAfter})
-	}
+	and = append(and, q.predicates()...)
+	and = append(and, q.scope.predicates()...)
 
 	if q.PageToken != "" {
 		cursor, err := pager.DecodeCursor(q.PageToken)
 		if err != nil {
-			return "", nil, err
+			return "", nil, errInvalidPageToken(err)
 		}
 		and = append(and, sq.Expr("id < ?", cursor.ID))
 	}
@@ -66,7 +69,40 @@ func (q *TicketQuery) ToSql() (string, []any, error) {
 	return and.ToSql()
 }
 
-func listTickets(ctx context.Context, db *sql.DB, in *TicketQuery) ([]*Ticket, error) {
+// Match reports whether t matches the filters of the query, as ToSql would
+// select it. Status matches a part of the status stored in the view, such as
+// IT(RESOLVE), ignoring case as the collation of the view does.
+// The query must be resolved first.
+func (q *TicketQuery) Match(t *Ticket) bool {
+	switch {
+	case q.ID != "" && q.ID != t.ID:
+		return false
+	case q.Status != "" && !strings.Contains(strings.ToUpper(t.rawStatus), strings.ToUpper(q.Status)):
+		return false
+	case q.Priority != "" && q.Priority != t.Priority:
+		return false
+	case q.Category != "" && q.Category != t.Category:
+		return false
+	case q.Number != "" && q.Number != t.Number:
+		return false
+	case q.EmployeeID != "" && q.EmployeeID != t.Employee.ID:
+		return false
+	}
+
+	return q.scope.match(t) && q.contains(t.CreatedAt)
+}
+
+// statusLike selects the tickets whose status in the view contains status.
+// The wildcards of LIKE are escaped, so status matches literally.
+func statusLike(status string) sq.Sqlizer {
+	escaped := strings.NewReplacer("[", "[[]", "%", "[%]", "_", "[_]").Replace(status)
+	return sq.Like{"status": "%" + escaped + "%"}
+}
+
+func listTickets(ctx context.Context, db *sql.DB, in *TicketQuery) (_ []*Ticket, err error) {
+	ctx, qs := startQuery(ctx, "listTickets")
+	defer qs.end(&err)
+
 	id := fmt.Sprintf("TOP %d id", pager.Size(in.PageSize))
 	pred, args, err := in.ToSql()
 	if err != nil {
@@ -98,9 +134,10 @@ func listTickets(ctx context.Context, db *sql.DB, in *TicketQuery) ([]*Ticket, e
 		OrderBy("id DESC").
 		MustSql()
 
+	qs.statement(q)
 	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, dbError("failed to execute query", err)
 	}
 	defer rows.Close()
 
@@ -135,10 +172,13 @@ func listTickets(ctx context.Context, db *sql.DB, in *TicketQuery) ([]*Ticket, e
 		}
 
 		s.Status = mapTicketStatus(status)
+		s.rawStatus = status
+		s.CreatedAt = fromDBTime(s.CreatedAt, in.dbLoc, in.loc)
+		s.ClosedDate = fromDBTime(s.ClosedDate, in.dbLoc, in.loc)
 		tickets = append(tickets, &s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+		return nil, dbError("failed to iterate rows", err)
 	}
 
 	return tickets, nil
@@ -177,23 +217,27 @@ func mapTicketStatus(status string) string {
 }
 
 type BatchGetTicketsQuery struct {
-	ID            string    `json:"id" query:"id"`
-	Number        string    `json:"number" query:"number"`
-	Category      string    `json:"category" query:"category"`
-	Priority      string    `json:"priority" query:"priority"`
-	Status        string    `json:"status" query:"status"`
-	RequesterID   string    `json:"requesterId" query:"requesterId"`
-	CreatedBefore time.Time `json:"createdBefore" query:"createdBefore"`
-	CreatedAfter  time.Time `json:"createdAfter" query:"createdAfter"`
-
+	ID          string `json:"id" query:"id"`
+	Number      string `json:"number" query:"number"`
+	Category    string `json:"category" query:"category"`
+	Priority    string `json:"priority" query:"priority"`
+	Status      string `json:"status" query:"status"`
+	RequesterID string `json:"requesterId" query:"requesterId"`
+	Template    string `json:"template" query:"template"`
+	DateRange
+
+	scope  scope
 	nextID string
+
+	// ids restricts the query to the tickets of the ids.
+	ids []string
 }
 
 func (q *BatchGetTicketsQuery) ToSql() (string, []any, error) {
 	and := sq.And{}
 
 	if q.Status != "" {
-		and = append(and, sq.Expr("status LIKE %?%", q.Status))
+		and = append(and, statusLike(q.Status))
 	}
 
 	if q.Priority != "" {
@@ -211,21 +255,28 @@ func (q *BatchGetTicketsQuery) ToSql() (string, []any, error) {
 	if q.RequesterID != "" {
 		and = append(and, sq.Eq{"creator_number": q.RequesterID})
 	}
-	if !q.CreatedBefore.IsZero() {
-		and = append(and, sq.LtOrEq{"created_at": q.CreatedBefore})
-	}
-	if !q.CreatedAfter.IsZero() {
-		and = append(and, sq.GtOrEq{"created_at": q.CreatedAfter})
-	}
+	and = append(and, q.predicates()...)
+	and = append(and, q.scope.predicates()...)
 
 	if q.nextID != "" {
 		and = append(and, sq.Lt{"id": q.nextID})
 	}
 
+	if len(q.ids) > 0 {
+		and = append(and, sq.Eq{"id": q.ids})
+	}
+
 	return and.ToSql()
 }
 
-func batchGetTickets(ctx context.Context, db *sql.DB, batchSize int, nextID string, in *BatchGetTicketsQuery) ([]*Ticket, error) {
+// ticketsPerBatch is the number of tickets read per query by exports and
+// the watcher.
+const ticketsPerBatch = 200
+
+func batchGetTickets(ctx context.Context, db *sql.DB, batchSize int, nextID string, in *BatchGetTicketsQuery) (_ []*Ticket, err error) {
+	ctx, qs := startQuery(ctx, "batchGetTickets")
+	defer qs.end(&err)
+
 	id := fmt.Sprintf("TOP %d id", batchSize)
 	in.nextID = nextID
 	pred, args, err := in.ToSql()
@@ -258,9 +309,10 @@ func batchGetTickets(ctx context.Context, db *sql.DB, batchSize int, nextID stri
 		OrderBy("id DESC").
 		MustSql()
 
+	qs.statement(q)
 	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, dbError("failed to execute query", err)
 	}
 	defer rows.Close()
 
@@ -295,10 +347,13 @@ func batchGetTickets(ctx context.Context, db *sql.DB, batchSize int, nextID stri
 		}
 
 		s.Status = mapTicketStatus(status)
+		s.rawStatus = status
+		s.CreatedAt = fromDBTime(s.CreatedAt, in.dbLoc, in.loc)
+		s.ClosedDate = fromDBTime(s.ClosedDate, in.dbLoc, in.loc)
 		tickets = append(tickets, &s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+		return nil, dbError("failed to iterate rows", err)
 	}
 
 	return tickets, nil
@@ -330,23 +385,25 @@ type PriorityReport struct {
 }
 
 type ReportQuery struct {
-	CreatedBefore time.Time `json:"createdBefore" query:"createdBefore"`
-	CreatedAfter  time.Time `json:"createdAfter" query:"createdAfter"`
+	DateRange
+
+	// scope filters the outer query of the report CTEs, so each CTE must
+	// select the scope columns: branch, department and creator_number.
+	scope scope
 }
 
 func (q *ReportQuery) ToSql() (string, []any, error) {
 	and := sq.And{}
-	if !q.CreatedBefore.IsZero() {
-		and = append(and, sq.LtOrEq{"created_at": q.CreatedBefore})
-	}
-	if !q.CreatedAfter.IsZero() {
-		and = append(and, sq.GtOrEq{"created_at": q.CreatedAfter})
-	}
+	and = append(and, q.predicates()...)
+	and = append(and, q.scope.predicates()...)
 
 	return and.ToSql()
 }
 
-func listPriorityReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*PriorityReport, error) {
+func listPriorityReports(ctx context.Context, db *sql.DB, in *ReportQuery) (_ []*PriorityReport, err error) {
+	ctx, qs := startQuery(ctx, "listPriorityReports")
+	defer qs.end(&err)
+
 	pred, args, err := in.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert to sql: %w", err)
@@ -366,7 +423,10 @@ func listPriorityReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*P
 			SELECT
 				category,
 				priority,
-				created_at
+				created_at,
+				branch,
+				department,
+				creator_number
 			FROM v_hepldesk_ticket_report
 		)`).
 		From("priority_report").
@@ -376,10 +436,12 @@ func listPriorityReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*P
 		Where(pred, args...).
 		MustSql()
 
+	qs.statement(q)
 	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, dbError("failed to execute query", err)
 	}
+	defer rows.Close()
 
 	reports := make([]*PriorityReport, 0)
 	for rows.Next() {
@@ -397,18 +459,21 @@ func listPriorityReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*P
 		}
 
 		if s.Name == "" {
-			s.Name = "(Blank)"
+			s.Name = blankName
 		}
 		reports = append(reports, &s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+		return nil, dbError("failed to iterate rows", err)
 	}
 
 	return reports, nil
 }
 
-func listCategoryReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*CategoryReport, error) {
+func listCategoryReports(ctx context.Context, db *sql.DB, in *ReportQuery) (_ []*CategoryReport, err error) {
+	ctx, qs := startQuery(ctx, "listCategoryReports")
+	defer qs.end(&err)
+
 	pred, args, err := in.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert to sql: %w", err)
@@ -426,7 +491,10 @@ func listCategoryReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*C
 				SELECT
 					category,
 					status,
-					created_at
+					created_at,
+					branch,
+					department,
+					creator_number
 				FROM v_hepldesk_ticket_report
 			)
 		`).
@@ -437,10 +505,12 @@ func listCategoryReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*C
 		Where(pred, args...).
 		MustSql()
 
+	qs.statement(q)
 	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, dbError("failed to execute query", err)
 	}
+	defer rows.Close()
 
 	reports := make([]*CategoryReport, 0)
 	for rows.Next() {
@@ -457,18 +527,21 @@ func listCategoryReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*C
 		}
 
 		if s.Name == "" {
-			s.Name = "(Blank)"
+			s.Name = blankName
 		}
 		reports = append(reports, &s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+		return nil, dbError("failed to iterate rows", err)
 	}
 
 	return reports, nil
 }
 
-func listSupporterReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*SupporterReport, error) {
+func listSupporterReports(ctx context.Context, db *sql.DB, in *ReportQuery) (_ []*SupporterReport, err error) {
+	ctx, qs := startQuery(ctx, "listSupporterReports")
+	defer qs.end(&err)
+
 	pred, args, err := in.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert to sql: %w", err)
@@ -487,7 +560,10 @@ func listSupporterReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*
 				SELECT
 					supporter_name,
 					status,
-					created_at
+					created_at,
+					branch,
+					department,
+					creator_number
 				FROM v_hepldesk_ticket_report
 			)
 		`).
@@ -498,10 +574,12 @@ func listSupporterReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*
 		Where(pred, args...).
 		MustSql()
 
+	qs.statement(q)
 	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, dbError("failed to execute query", err)
 	}
+	defer rows.Close()
 
 	reports := make([]*SupporterReport, 0)
 	for rows.Next() {
@@ -518,13 +596,149 @@ func listSupporterReports(ctx context.Context, db *sql.DB, in *ReportQuery) ([]*
 		}
 
 		if s.Name == "" {
-			s.Name = "(Blank)"
+			s.Name = blankName
+		}
+		reports = append(reports, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("failed to iterate rows", err)
+	}
+
+	return reports, nil
+}
+
+type MonthlyReport struct {
+	Month string `json:"month"` // YYYY-MM
+	Total int64  `json:"total"`
+}
+
+func listMonthlyReports(ctx context.Context, db *sql.DB, in *ReportQuery) (_ []*MonthlyReport, err error) {
+	ctx, qs := startQuery(ctx, "listMonthlyReports")
+	defer qs.end(&err)
+
+	pred, args, err := in.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert to sql: %w", err)
+	}
+
+	// An open range spans the tickets it selects.
+	from, until := in.from, in.until
+	if from.IsZero() || until.IsZero() {
+		first, last, ok, err := createdAtBounds(ctx, db, pred, args, in)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return []*MonthlyReport{}, nil
 		}
+		if from.IsZero() {
+			from = first
+		}
+		if until.IsZero() {
+			until = last.Add(time.Nanosecond)
+		}
+	}
+
+	// Buckets follow the calendar of the request's time zone. Each month
+	// is bounded in the database zone on its own, as the offset between
+	// the zones may change from one month to the next.
+	starts := monthStarts(from, until, in.loc)
+	if len(starts) == 0 {
+		return []*MonthlyReport{}, nil
+	}
+	if len(starts) > maxMonths+1 {
+		starts = starts[len(starts)-maxMonths-1:]
+	}
+	values := make([]string, 0, len(starts)-1)
+	monthArgs := make([]any, 0, 3*(len(starts)-1))
+	for i := range len(starts) - 1 {
+		values = append(values, "(?, ?, ?)")
+		monthArgs = append(monthArgs,
+			starts[i].Format("2006-01"),
+			dbTime(starts[i], in.dbLoc),
+			dbTime(starts[i+1], in.dbLoc),
+		)
+	}
+
+	q, args := sq.
+		Select(
+			`months.month`,
+			`COUNT(*) AS total`,
+		).
+		Prefix(`
+			WITH months AS (
+				SELECT month, start_at, end_at
+				FROM (VALUES `+strings.Join(values, ", ")+`) AS m (month, start_at, end_at)
+			),
+			monthly_report AS (
+				SELECT
+					created_at,
+					branch,
+					department,
+					creator_number
+				FROM v_hepldesk_ticket_report
+			)
+		`, monthArgs...).
+		From(`monthly_report`).
+		Join(`months ON monthly_report.created_at >= months.start_at AND monthly_report.created_at < months.end_at`).
+		PlaceholderFormat(sq.AtP).
+		GroupBy("months.month").
+		OrderBy("months.month ASC").
+		Where(pred, args...).
+		MustSql()
+
+	qs.statement(q)
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, dbError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	reports := make([]*MonthlyReport, 0)
+	for rows.Next() {
+		var s MonthlyReport
+		err := rows.Scan(
+			&s.Month,
+			&s.Total,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
 		reports = append(reports, &s)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+		return nil, dbError("failed to iterate rows", err)
 	}
 
 	return reports, nil
 }
+
+// maxMonths bounds the months of a monthly report, keeping the parameters
+// of its query below the 2100 SQL Server accepts.
+const maxMonths = 600
+
+// createdAtBounds returns the creation times of the first and last ticket
+// selected by pred, in the zone of the request. It returns false if no
+// ticket is selected.
+func createdAtBounds(ctx context.Context, db *sql.DB, pred string, args []any, in *ReportQuery) (first, last time.Time, ok bool, err error) {
+	q, args := sq.
+		Select(
+			`MIN(created_at)`,
+			`MAX(created_at)`,
+		).
+		From(`v_hepldesk_ticket_report`).
+		PlaceholderFormat(sq.AtP).
+		Where(pred, args...).
+		MustSql()
+
+	var minAt, maxAt sql.NullTime
+	if err := db.QueryRowContext(ctx, q, args...).Scan(&minAt, &maxAt); err != nil {
+		return time.Time{}, time.Time{}, false, dbError("failed to execute query", err)
+	}
+	if !minAt.Valid || !maxAt.Valid {
+		return time.Time{}, time.Time{}, false, nil
+	}
+
+	return fromDBTime(minAt.Time, in.dbLoc, in.loc), fromDBTime(maxAt.Time, in.dbLoc, in.loc), true, nil
+}
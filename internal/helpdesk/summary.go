@@ -0,0 +1,69 @@
+package helpdesk
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// summaryReport holds the reports printed on the summary of an export.
+type summaryReport struct {
+	categories []*CategoryReport
+	supporters []*SupporterReport
+	priorities []*PriorityReport
+	months     []*MonthlyReport
+}
+
+// listSummaryReports returns the reports of the query through the report
+// cache. The result is shared and must not be modified.
+func (s *Service) listSummaryReports(ctx context.Context, zlog *zap.Logger, in *ReportQuery) (*summaryReport, error) {
+	return s.reports.get(ctx, in.cacheKey(), func(ctx context.Context) (*summaryReport, error) {
+		return s.querySummaryReports(ctx, zlog, in)
+	})
+}
+
+func (s *Service) querySummaryReports(ctx context.Context, zlog *zap.Logger, in *ReportQuery) (*summaryReport, error) {
+	categoryReports, err := listCategoryReports(ctx, s.db, in)
+	if err != nil {
+		zlog.Error("failed to list category reports", zap.Error(err))
+		return nil, err
+	}
+
+	supporterReports, err := listSupporterReports(ctx, s.db, in)
+	if err != nil {
+		zlog.Error("failed to list supporter reports", zap.Error(err))
+		return nil, err
+	}
+
+	priorityReports, err := listPriorityReports(ctx, s.db, in)
+	if err != nil {
+		zlog.Error("failed to list priority reports", zap.Error(err))
+		return nil, err
+	}
+
+	monthlyReports, err := listMonthlyReports(ctx, s.db, in)
+	if err != nil {
+		zlog.Error("failed to list monthly reports", zap.Error(err))
+		return nil, err
+	}
+
+	return &summaryReport{
+		categories: categoryReports,
+		supporters: supporterReports,
+		priorities: priorityReports,
+		months:     monthlyReports,
+	}, nil
+}
+
+// period returns the first and last day covered by the range formatted for
+// a report header. An open range ends today.
+func (r *DateRange) period() (from, to string) {
+	first, last := r.Bounds()
+	to = time.Now().In(r.loc).Format("02/01/2006")
+	from = first.Format("02/01/2006")
+	if !last.IsZero() {
+		to = last.Format("02/01/2006")
+	}
+	return from, to
+}
@@ -0,0 +1,240 @@
+package helpdesk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// DefaultExportTemplate is the name of the template used when an export
+// does not select one.
+const DefaultExportTemplate = "default"
+
+// ExportColumn is a column of an exported ticket sheet.
+type ExportColumn struct {
+	// Field is the ticket field written to the column, e.g. createdAt.
+	Field string `json:"field"`
+
+	// Label is the header of the column.
+	Label string `json:"label"`
+
+	// DateFormat is the Go layout of date fields, e.g. 02/01/2006.
+	// Defaults to the format of the field in the default template.
+	DateFormat string `json:"dateFormat,omitempty"`
+}
+
+// ExportTemplate selects, orders and labels the columns of an export.
+type ExportTemplate struct {
+	Name    string         `json:"-"`
+	Columns []ExportColumn `json:"columns"`
+}
+
+type ticketField struct {
+	// value returns a string, or a time.Time for date fields.
+	value      func(*Ticket) any
+	dateFormat string
+	wrap       bool
+	highlights map[string]string
+
+	// translate reports whether the value is a label to translate.
+	translate bool
+}
+
+// ticketFields are the fields a template can export.
+var ticketFields = map[string]ticketField{
+	"id":                {value: func(t *Ticket) any { return t.ID }},
+	"number":            {value: func(t *Ticket) any { return t.Number }},
+	"category":          {value: func(t *Ticket) any { return t.Category }},
+	"title":             {value: func(t *Ticket) any { return t.Title }},
+	"description":       {value: func(t *Ticket) any { return t.Description }, wrap: true},
+	"createdAt":         {value: func(t *Ticket) any { return t.CreatedAt }, dateFormat: "02/01/2006 15:04:05"},
+	"employeeId":        {value: func(t *Ticket) any { return t.Employee.ID }},
+	"employeeName":      {value: func(t *Ticket) any { return t.Employee.DisplayName }},
+	"position":          {value: func(t *Ticket) any { return t.Employee.Position }},
+	"department":        {value: func(t *Ticket) any { return t.Employee.Department }},
+	"branch":            {value: func(t *Ticket) any { return t.Employee.Branch }},
+	"supporterName":     {value: func(t *Ticket) any { return t.Supporter.DisplayName }},
+	"supporterPosition": {value: func(t *Ticket) any { return t.Supporter.Position }},
+	"priority":          {value: func(t *Ticket) any { return t.Priority }, highlights: priorityHighlights, translate: true},
+	"status":            {value: func(t *Ticket) any { return t.Status }, highlights: statusHighlights, translate: true},
+	"closedDate":        {value: func(t *Ticket) any { return t.ClosedDate }, dateFormat: "02/01/2006"},
+}
+
+func defaultExportTemplate() *ExportTemplate {
+	return &ExportTemplate{
+		Name: DefaultExportTemplate,
+		Columns: []ExportColumn{
+			{Field: "number", Label: "HelpDesk Number"},
+			{Field: "category", Label: "Type of Form"},
+			{Field: "title", Label: "Title"},
+			{Field: "description", Label: "Description"},
+			{Field: "createdAt", Label: "Request Date"},
+			{Field: "employeeId", Label: "ID Staff Request"},
+			{Field: "employeeName", Label: "Request by (Eng)"},
+			{Field: "position", Label: "Position"},
+			{Field: "department", Label: "Department"},
+			{Field: "branch", Label: "Branch"},
+			{Field: "supporterName", Label: "IT Support Name"},
+			{Field: "supporterPosition", Label: "IT Support Position"},
+			{Field: "priority", Label: "Priority"},
+			{Field: "status", Label: "Status"},
+			{Field: "closedDate", Label: "Closed Date"},
+		},
+	}
+}
+
+// LoadExportTemplates reads export templates from a JSON file of the form
+//
+//	{"templates": {"hr": {"columns": [{"field": "number", "label": "No."}]}}}
+func LoadExportTemplates(path string) (map[string]*ExportTemplate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export templates: %w", err)
+	}
+
+	var file struct {
+		Templates map[string]*ExportTemplate `json:"templates"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse export templates: %w", err)
+	}
+
+	for name, t := range file.Templates {
+		t.Name = name
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("invalid export template %q: %w", name, err)
+		}
+	}
+
+	return file.Templates, nil
+}
+
+func (t *ExportTemplate) validate() error {
+	if len(t.Columns) == 0 {
+		return fmt.Errorf("no columns")
+	}
+
+	for i, c := range t.Columns {
+		f, ok := ticketFields[c.Field]
+		if !ok {
+			return fmt.Errorf("column %d: unknown field %q", i+1, c.Field)
+		}
+		if c.Label == "" {
+			return fmt.Errorf("column %d: label is empty", i+1)
+		}
+		if c.DateFormat != "" && f.dateFormat == "" {
+			return fmt.Errorf("column %d: field %q is not a date", i+1, c.Field)
+		}
+	}
+
+	return nil
+}
+
+// dateFormat returns the Go layout of the column, if its field is a date.
+func (c ExportColumn) dateFormat() string {
+	if c.DateFormat != "" {
+		return c.DateFormat
+	}
+	return ticketFields[c.Field].dateFormat
+}
+
+// exportTemplate returns the template with the given name.
+// If the name is empty, it returns the default template.
+func (s *Service) exportTemplate(name string) (*ExportTemplate, error) {
+	if name == "" {
+		name = DefaultExportTemplate
+	}
+
+	if t, ok := s.templates[name]; ok {
+		return t, nil
+	}
+	if name == DefaultExportTemplate {
+		return defaultExportTemplate(), nil
+	}
+
+	st, _ := status.New(codes.InvalidArgument, "Template does not exist.").
+		WithDetails(&edpb.BadRequest{
+			FieldViolations: []*edpb.BadRequest_FieldViolation{
+				{
+					Field:       "template",
+					Description: "unknown export template " + name,
+				},
+			},
+		})
+	return nil, st.Err()
+}
+
+// goLayoutToNumFmt converts a Go time layout to an Excel number format.
+// Other letters are escaped so Excel shows them as they are.
+func goLayoutToNumFmt(layout string) string {
+	tokens := []struct{ layout, numFmt string }{
+		{"January", "mmmm"},
+		{"Monday", "dddd"},
+		{"2006", "yyyy"},
+		{"Jan", "mmm"},
+		{"Mon", "ddd"},
+		{"01", "mm"},
+		{"02", "dd"},
+		{"03", "hh"},
+		{"04", "mm"},
+		{"05", "ss"},
+		{"06", "yy"},
+		{"15", "hh"},
+		{"PM", "AM/PM"},
+		{"1", "m"},
+		{"2", "d"},
+		{"3", "h"},
+	}
+
+	var b strings.Builder
+next:
+	for len(layout) > 0 {
+		for _, t := range tokens {
+			if strings.HasPrefix(layout, t.layout) {
+				b.WriteString(t.numFmt)
+				layout = layout[len(t.layout):]
+				continue next
+			}
+		}
+
+		c := layout[0]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+		layout = layout[1:]
+	}
+
+	return b.String()
+}
+
+// exportValue returns the value of the column for t in the language tag.
+// Blank dates are returned as nil.
+func (c ExportColumn) exportValue(t *Ticket, lang language.Tag) any {
+	f := ticketFields[c.Field]
+	switch v := f.value(t).(type) {
+	case time.Time:
+		if v.IsZero() || isBlankDate(v) {
+			return nil
+		}
+		return v
+
+	case string:
+		if f.translate {
+			return i18n.T(lang, v)
+		}
+		return v
+
+	default:
+		return v
+	}
+}
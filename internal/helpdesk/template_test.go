@@ -0,0 +1,30 @@
+package helpdesk
+
+import "testing"
+
+func TestGoLayoutToNumFmt(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{layout: "02/01/2006", want: "dd/mm/yyyy"},
+		{layout: "02/01/2006 15:04:05", want: "dd/mm/yyyy hh:mm:ss"},
+		{layout: "2006-01-02", want: "yyyy-mm-dd"},
+		{layout: "2/1/06", want: "d/m/yy"},
+		{layout: "03:04 PM", want: "hh:mm AM/PM"},
+		{layout: "3:04PM", want: "h:mmAM/PM"},
+		{layout: "Monday, 2 January 2006", want: "dddd, d mmmm yyyy"},
+		{layout: "Mon 02 Jan", want: "ddd dd mmm"},
+		{layout: "2006-01-02T15:04", want: "yyyy-mm-dd\\Thh:mm"},
+		{layout: "at 15h", want: "\\a\\t hh\\h"},
+		{layout: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			if got := goLayoutToNumFmt(tt.layout); got != tt.want {
+				t.Errorf("goLayoutToNumFmt(%q) = %q, want %q", tt.layout, got, tt.want)
+			}
+		})
+	}
+}
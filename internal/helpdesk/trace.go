@@ -0,0 +1,57 @@
+package helpdesk
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk")
+
+// endSpan ends span, marking it failed if err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// querySpan measures a database query in the metrics and the trace.
+type querySpan struct {
+	name  string
+	start time.Time
+	span  trace.Span
+}
+
+// startQuery starts measuring the query name in a span child of ctx.
+func startQuery(ctx context.Context, name string) (context.Context, *querySpan) {
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMSSQL,
+			semconv.DBOperationName(name),
+		),
+	)
+	return ctx, &querySpan{name: name, start: time.Now(), span: span}
+}
+
+// statement records the statement of the query. Values are always bound
+// as parameters, so the statement holds none and only its whitespace is
+// collapsed.
+func (q *querySpan) statement(stmt string) {
+	q.span.SetAttributes(semconv.DBQueryText(strings.Join(strings.Fields(stmt), " ")))
+}
+
+// end ends the query. It is deferred with the address of the named error
+// result of the query.
+func (q *querySpan) end(err *error) {
+	metrics.ObserveQuery(q.name, time.Since(q.start), *err)
+	endSpan(q.span, *err)
+}
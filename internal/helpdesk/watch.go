@@ -0,0 +1,272 @@
+package helpdesk
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+)
+
+// Types of TicketEvent.
+const (
+	EventTicketCreated = "ticket.created"
+	EventTicketUpdated = "ticket.updated"
+)
+
+// TicketEvent reports a ticket that was created or whose status changed.
+type TicketEvent struct {
+	Type      string    `json:"type"`
+	Ticket    *Ticket   `json:"ticket"`
+	OldStatus string    `json:"oldStatus,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Watcher detects created and updated tickets by polling the view.
+// A single Watcher serves any number of subscribers.
+type Watcher struct {
+	svc      *Service
+	interval time.Duration
+	lookback time.Duration
+	zlog     *zap.Logger
+
+	mu     sync.Mutex
+	subs   map[chan *TicketEvent]struct{}
+	status map[string]string // ticket id to status of the last poll
+}
+
+// NewWatcher returns a Watcher polling every interval for tickets created
+// within lookback. Status changes of older tickets are not detected.
+// Each poll reads the id and status of every ticket within lookback, so
+// the lookback bounds the cost of polling.
+func (s *Service) NewWatcher(interval, lookback time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	if lookback <= 0 {
+		return nil, errors.New("lookback must be positive")
+	}
+
+	return &Watcher{
+		svc:      s,
+		interval: interval,
+		lookback: lookback,
+		zlog:     s.zlog.With(zap.String("component", "watcher")),
+		subs:     make(map[chan *TicketEvent]struct{}),
+	}, nil
+}
+
+// Subscribe returns a channel receiving events and a function to cancel
+// the subscription. Events are dropped if the channel buffer is full.
+func (w *Watcher) Subscribe(buffer int) (<-chan *TicketEvent, func()) {
+	ch := make(chan *TicketEvent, buffer)
+
+	w.mu.Lock()
+	w.subs[ch] = struct{}{}
+	w.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			w.mu.Lock()
+			delete(w.subs, ch)
+			w.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Run polls until ctx is done. The first poll only records the current
+// tickets so existing tickets are not reported as created.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.zlog.Error("failed to poll tickets", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) (err error) {
+	w.mu.Lock()
+	if len(w.subs) == 0 {
+		// Nobody listens, so forget the tickets and prime again once
+		// someone subscribes.
+		w.status = nil
+		w.mu.Unlock()
+		return nil
+	}
+	w.mu.Unlock()
+
+	ctx, span := tracer.Start(ctx, "Watcher.poll")
+	defer func() { endSpan(span, err) }()
+
+	now := time.Now()
+	in := &BatchGetTicketsQuery{}
+	in.loc, in.dbLoc = w.svc.loc, w.svc.loc
+	in.from = now.Add(-w.lookback)
+
+	// Each poll reads only the id and status of the tickets in the
+	// lookback window, and the full rows of the tickets that changed.
+	status, err := listTicketStatuses(ctx, w.svc.db, &in.DateRange)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	primed := w.status != nil
+	prev := w.status
+	w.mu.Unlock()
+
+	events := make([]*TicketEvent, 0)
+	if primed {
+		changed := make([]string, 0)
+		for id, s := range status {
+			if old, seen := prev[id]; !seen || old != s {
+				changed = append(changed, id)
+			}
+		}
+
+		for len(changed) > 0 {
+			n := min(len(changed), ticketsPerBatch)
+			in.ids = changed[:n]
+			changed = changed[n:]
+
+			tickets, err := batchGetTickets(ctx, w.svc.db, n, "", in)
+			if err != nil {
+				return err
+			}
+
+			for _, t := range tickets {
+				old, seen := prev[t.ID]
+				switch {
+				case !seen:
+					events = append(events, &TicketEvent{Type: EventTicketCreated, Ticket: t, Time: now})
+				case old != t.Status:
+					events = append(events, &TicketEvent{Type: EventTicketUpdated, Ticket: t, OldStatus: old, Time: now})
+				}
+			}
+		}
+
+		sort.SliceStable(events, func(i, j int) bool {
+			return events[i].Ticket.CreatedAt.Before(events[j].Ticket.CreatedAt)
+		})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = status
+
+	// Events are sorted oldest first.
+	for i := range events {
+		for ch := range w.subs {
+			select {
+			case ch <- events[i]:
+			default:
+				w.zlog.Warn("dropped ticket event for a slow subscriber", zap.String("ticketId", events[i].Ticket.ID))
+			}
+		}
+	}
+
+	return nil
+}
+
+// listTicketStatuses returns the status of the tickets created within r by
+// ticket id, mapped as Ticket.Status.
+func listTicketStatuses(ctx context.Context, db *sql.DB, r *DateRange) (_ map[string]string, err error) {
+	ctx, qs := startQuery(ctx, "listTicketStatuses")
+	defer qs.end(&err)
+
+	q, args := sq.
+		Select("id", "status").
+		From("v_hepldesk_ticket_report").
+		Where(sq.And(r.predicates())).
+		PlaceholderFormat(sq.AtP).
+		MustSql()
+
+	qs.statement(q)
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, dbError("failed to execute query", err)
+	}
+	defer rows.Close()
+
+	status := make(map[string]string)
+	for rows.Next() {
+		var id, s string
+		if err := rows.Scan(&id, &s); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		status[id] = mapTicketStatus(s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("failed to iterate rows", err)
+	}
+
+	return status, nil
+}
+
+// Watch returns the events matching in until ctx is done.
+// The channel is closed once ctx is done.
+func (w *Watcher) Watch(ctx context.Context, in *TicketQuery) (<-chan *TicketEvent, error) {
+	if err := in.resolve(time.Now(), w.svc.loc); err != nil {
+		return nil, err
+	}
+	in.scope = scopeOf(ctx, w.zlog)
+
+	events, cancel := w.Subscribe(64)
+	out := make(chan *TicketEvent)
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if !in.Match(e.Ticket) {
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// PriorityLevel returns the priority as HIGH, MEDIUM or LOW, correcting the
+// spelling of the values stored in the view.
+func PriorityLevel(priority string) string {
+	switch p := strings.ToUpper(strings.TrimSpace(priority)); p {
+	case "HIGHT":
+		return "HIGH"
+	case "MEDIUEM":
+		return "MEDIUM"
+	default:
+		return p
+	}
+}
@@ -0,0 +1,92 @@
+package helpdesk
+
+import (
+	"time"
+
+	"github.com/golang-sql/civil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// DefaultTimeZone is the IANA name of the zone the helpdesk database records
+// its timestamps in. It is also used for requests that do not set a timeZone.
+const DefaultTimeZone = "Asia/Vientiane"
+
+// resolveLocation returns the location named by the IANA time zone name.
+// If the name is empty, it returns fallback.
+func resolveLocation(name string, fallback *time.Location) (*time.Location, error) {
+	if name == "" {
+		return fallback, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		s, _ := status.New(codes.InvalidArgument, "Time zone must be a valid IANA time zone name.").
+			WithDetails(&edpb.BadRequest{
+				FieldViolations: []*edpb.BadRequest_FieldViolation{
+					{
+						Field:       "timeZone",
+						Description: "unknown time zone " + name,
+					},
+				},
+			})
+		return nil, s.Err()
+	}
+
+	return loc, nil
+}
+
+// dbTime converts t to the wall clock of the database zone.
+// The view stores datetime columns without an offset, so a civil value
+// must be bound to compare them against the same wall clock.
+func dbTime(t time.Time, dbLoc *time.Location) civil.DateTime {
+	if dbLoc != nil {
+		t = t.In(dbLoc)
+	}
+	return civil.DateTimeOf(t)
+}
+
+// fromDBTime reinterprets a datetime scanned from the database, which the
+// driver returns as UTC, as a wall clock in dbLoc and converts it to loc.
+// SQL Server's default datetime 1900-01-01 marks an unset date and is
+// returned untouched.
+func fromDBTime(t time.Time, dbLoc, loc *time.Location) time.Time {
+	if t.IsZero() || dbLoc == nil || isBlankDate(t) {
+		return t
+	}
+
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), dbLoc)
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+func isBlankDate(t time.Time) bool {
+	return t.Format("2006-01-02") == "1900-01-01"
+}
+
+// monthStarts returns the start of each calendar month of loc that
+// overlaps [from, until), followed by the start of the month after them.
+// Months start at local midnight of their first day, whatever offset is in
+// effect then.
+func monthStarts(from, until time.Time, loc *time.Location) []time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if !from.Before(until) {
+		return nil
+	}
+
+	from = from.In(loc)
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, loc)
+
+	var starts []time.Time
+	for month.Before(until) {
+		starts = append(starts, month)
+		month = time.Date(month.Year(), month.Month()+1, 1, 0, 0, 0, 0, loc)
+	}
+	return append(starts, month)
+}
@@ -0,0 +1,56 @@
+package helpdesk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonthStarts(t *testing.T) {
+	vientiane, err := time.LoadLocation("Asia/Vientiane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, time.February, 10, 12, 0, 0, 0, newYork)
+	until := time.Date(2024, time.April, 1, 0, 0, 0, 0, newYork)
+	starts := monthStarts(from, until, newYork)
+
+	want := []string{"2024-02-01", "2024-03-01", "2024-04-01"}
+	if len(starts) != len(want) {
+		t.Fatalf("monthStarts() = %v, want starts of %v", starts, want)
+	}
+	for i, s := range starts {
+		if got := s.Format("2006-01-02 15:04"); got != want[i]+" 00:00" {
+			t.Errorf("start %d = %s, want local midnight of %s", i, got, want[i])
+		}
+	}
+
+	// New York is on standard time on 1 March and on daylight time on
+	// 1 April, so the months start at different hours in Vientiane.
+	if got := dbTime(starts[1], vientiane).String(); got != "2024-03-01T12:00:00" {
+		t.Errorf("start of March in Vientiane = %s, want 2024-03-01T12:00:00", got)
+	}
+	if got := dbTime(starts[2], vientiane).String(); got != "2024-04-01T11:00:00" {
+		t.Errorf("start of April in Vientiane = %s, want 2024-04-01T11:00:00", got)
+	}
+
+	// The range is read in the zone of the location.
+	starts = monthStarts(time.Date(2024, time.January, 31, 20, 0, 0, 0, time.UTC), time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC), vientiane)
+	if len(starts) != 2 || starts[0].Month() != time.February {
+		t.Errorf("monthStarts() = %v, want February only", starts)
+	}
+
+	// until is exclusive.
+	starts = monthStarts(time.Date(2024, time.January, 5, 0, 0, 0, 0, vientiane), time.Date(2024, time.February, 1, 0, 0, 0, 0, vientiane), vientiane)
+	if len(starts) != 2 || starts[0].Month() != time.January || starts[1].Month() != time.February {
+		t.Errorf("monthStarts() = %v, want January only", starts)
+	}
+
+	if starts := monthStarts(until, from, newYork); starts != nil {
+		t.Errorf("monthStarts() of an empty range = %v, want nil", starts)
+	}
+}
@@ -0,0 +1,105 @@
+// Package httperr maps the errors of handlers to gRPC statuses and HTTP
+// status codes, so that responses, logs, metrics, traces and audit entries
+// agree on the outcome of a request.
+package httperr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Status returns the status of err. A status wrapped by other errors is
+// returned as is, where status.FromError would replace its message with
+// the one of the wrapping errors. Errors that carry no status are Internal
+// without their message, which may reveal queries or paths.
+func Status(err error) *status.Status {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		if s := se.GRPCStatus(); s != nil {
+			return s
+		}
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return FromHTTP(he.Code)
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return withInfo(codes.DeadlineExceeded, "The request took too long.", "DEADLINE_EXCEEDED", "helpdesk")
+
+	case errors.Is(err, context.Canceled):
+		return withInfo(codes.Canceled, "The request was canceled.", "CANCELED", "helpdesk")
+
+	default:
+		return withInfo(codes.Internal, "An internal error occurred", "INTERNAL", "helpdesk")
+	}
+}
+
+// HTTPCode returns the HTTP status code err is written with. Echo errors
+// keep their status, such as 405, which has no gRPC code.
+func HTTPCode(err error) int {
+	var he *echo.HTTPError
+	if errors.As(err, &he) && he.Code >= http.StatusBadRequest {
+		return he.Code
+	}
+	return runtime.HTTPStatusFromCode(Status(err).Code())
+}
+
+// FromHTTP returns the status of an HTTP error raised by Echo, such as of
+// a route that does not exist.
+func FromHTTP(httpCode int) *status.Status {
+	reason := strings.ToUpper(strings.ReplaceAll(http.StatusText(httpCode), " ", "_"))
+
+	switch httpCode {
+	case http.StatusBadRequest:
+		return withInfo(codes.InvalidArgument, "Bad request.", reason, "http")
+
+	case http.StatusUnauthorized:
+		return withInfo(codes.Unauthenticated, "Unauthenticated.", reason, "http")
+
+	case http.StatusForbidden:
+		return withInfo(codes.PermissionDenied, "Permission denied.", reason, "http")
+
+	case http.StatusNotFound:
+		return withInfo(codes.NotFound, "Not found!", reason, "http")
+
+	case http.StatusMethodNotAllowed:
+		return withInfo(codes.Unimplemented, "Method is not allowed.", reason, "http")
+
+	case http.StatusRequestEntityTooLarge:
+		return withInfo(codes.InvalidArgument, "Request body is too large.", reason, "http")
+
+	case http.StatusUnsupportedMediaType:
+		return withInfo(codes.InvalidArgument, "Request body must be a valid JSON.", reason, "http")
+
+	case http.StatusTooManyRequests:
+		return withInfo(codes.ResourceExhausted, "Too many requests.", reason, "http")
+
+	case http.StatusServiceUnavailable:
+		return withInfo(codes.Unavailable, "Service is unavailable.", reason, "http")
+	}
+
+	if httpCode >= http.StatusInternalServerError {
+		return withInfo(codes.Internal, "An internal error occurred", "INTERNAL", "helpdesk")
+	}
+	return withInfo(codes.Unknown, "Unknown error!", reason, "http")
+}
+
+func withInfo(c codes.Code, msg, reason, domain string) *status.Status {
+	s, _ := status.New(c, msg).WithDetails(&edpb.ErrorInfo{
+		Reason: reason,
+		Domain: domain,
+	})
+	return s
+}
@@ -0,0 +1,90 @@
+// Package i18n translates user facing text of the API.
+//
+// Messages are looked up by their English text, so English needs no catalog
+// and any message missing from a catalog falls back to English.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Supported lists the supported languages. The first one is the default.
+var Supported = []language.Tag{
+	language.English,
+	language.Lao,
+}
+
+var (
+	matcher  = language.NewMatcher(Supported)
+	catalogs = mustLoadCatalogs()
+)
+
+func mustLoadCatalogs() map[language.Tag]map[string]string {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	catalogs := make(map[language.Tag]map[string]string, len(entries))
+	for _, e := range entries {
+		b, err := locales.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", e.Name(), err))
+		}
+
+		tag := language.MustParse(strings.TrimSuffix(e.Name(), ".json"))
+		catalogs[tag] = messages
+	}
+
+	return catalogs
+}
+
+// Negotiate returns the supported language that best matches lang, which
+// takes precedence, and the Accept-Language header value acceptLanguage.
+func Negotiate(lang, acceptLanguage string) language.Tag {
+	_, i := language.MatchStrings(matcher, lang, acceptLanguage)
+	return Supported[i]
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the language tag.
+func NewContext(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tag)
+}
+
+// FromContext returns the language carried by ctx, or the default language.
+func FromContext(ctx context.Context) language.Tag {
+	if tag, ok := ctx.Value(ctxKey{}).(language.Tag); ok {
+		return tag
+	}
+	return Supported[0]
+}
+
+// T translates msg to the language tag.
+func T(tag language.Tag, msg string) string {
+	if s, ok := catalogs[tag][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// Tf translates format to the language tag and formats it with args.
+func Tf(tag language.Tag, format string, args ...any) string {
+	return fmt.Sprintf(T(tag, format), args...)
+}
@@ -0,0 +1,130 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+// messageArgs are the functions whose argument at the index is the message
+// of a status returned to clients.
+var messageArgs = map[string]int{
+	"status.New":      1,
+	"status.Error":    1,
+	"Unauthenticated": 1,
+	"withInfo":        1,
+	"rangeViolation":  0,
+}
+
+// TestStatusMessages checks that every status message of the code base has
+// a Lao translation.
+func TestStatusMessages(t *testing.T) {
+	messages := statusMessages(t, filepath.Join("..", ".."))
+	if len(messages) == 0 {
+		t.Fatal("no status messages found")
+	}
+
+	for msg, pos := range messages {
+		if _, ok := catalogs[language.Lao][msg]; !ok {
+			t.Errorf("%s: %q has no Lao translation", pos, msg)
+		}
+	}
+}
+
+// statusMessages returns the literal status messages of the non-test Go
+// files under root, with their positions.
+func statusMessages(t *testing.T, root string) map[string]token.Position {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	messages := make(map[string]token.Position)
+	add := func(e ast.Expr) {
+		lit, ok := e.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			messages[s] = fset.Position(lit.Pos())
+		}
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "genproto" || strings.HasPrefix(d.Name(), ".") && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if i, ok := messageArgs[funcName(n.Fun)]; ok && i < len(n.Args) {
+					add(n.Args[i])
+				}
+
+			// Errors of package helpdesk carry their message.
+			case *ast.CompositeLit:
+				if id, ok := n.Type.(*ast.Ident); !ok || id.Name != "Error" {
+					return true
+				}
+				for _, e := range n.Elts {
+					if kv, ok := e.(*ast.KeyValueExpr); ok {
+						if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Message" {
+							add(kv.Value)
+						}
+					}
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return messages
+}
+
+// funcName returns the name of a called function: status.New for a
+// function of package status, and the bare name otherwise.
+func funcName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		if x, ok := f.X.(*ast.Ident); ok && x.Name == "status" {
+			return "status." + f.Sel.Name
+		}
+		return f.Sel.Name
+	}
+	return ""
+}
+
+func TestCatalogs(t *testing.T) {
+	for tag, messages := range catalogs {
+		for msg, translation := range messages {
+			if strings.Count(msg, "%") != strings.Count(translation, "%") {
+				t.Errorf("%s: %q and %q have different verbs", tag, msg, translation)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package jsonl appends records to JSON lines files.
+package jsonl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Appender appends values to a JSON lines file. The file is opened for
+// each value, so it can be rotated or removed meanwhile.
+// It is safe for concurrent use.
+type Appender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewAppender returns an Appender to the file at path, which is created
+// on the first append. An empty path discards the values.
+func NewAppender(path string) *Appender {
+	return &Appender{path: path}
+}
+
+// Append writes v as a line. It does nothing if a has no file.
+func (a *Appender) Append(v any) error {
+	if a == nil || a.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", a.path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", a.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	a := NewAppender(path)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.Append(map[string]int{"n": i}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("got %d lines, want 50", len(lines))
+	}
+	seen := make(map[int]bool)
+	for _, l := range lines {
+		var v map[string]int
+		if err := json.Unmarshal([]byte(l), &v); err != nil {
+			t.Fatalf("line %q: %v", l, err)
+		}
+		seen[v["n"]] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("got %d distinct values, want 50", len(seen))
+	}
+
+	// The file is reopened, so appends go on after it is removed.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Append("again"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "\"again\"\n" {
+		t.Errorf("file = %q, want the new line only", b)
+	}
+}
+
+func TestAppendNoFile(t *testing.T) {
+	var nilAppender *Appender
+	for _, a := range []*Appender{nilAppender, NewAppender("")} {
+		if err := a.Append("x"); err != nil {
+			t.Errorf("Append() = %v, want nil", err)
+		}
+	}
+
+	if err := NewAppender(t.TempDir()).Append(func() {}); err == nil {
+		t.Error("Append() of a func = nil error")
+	}
+}
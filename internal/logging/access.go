@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// probes are logged at debug level unless they fail, as they are
+// requested every few seconds.
+var probes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// AccessLog logs each request once it is served. Errors are handled here
+// so the status and size of error responses are logged too, and the
+// middlewares before it see the response instead of the error.
+func AccessLog(zlog *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			req, res := c.Request(), c.Response()
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			level := zapcore.InfoLevel
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				level = zapcore.ErrorLevel
+			case res.Status >= http.StatusBadRequest:
+				level = zapcore.WarnLevel
+			case probes[route]:
+				level = zapcore.DebugLevel
+			}
+
+			ce := With(req.Context(), zlog).Check(level, "served request")
+			if ce == nil {
+				return nil
+			}
+
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("route", route),
+				zap.String("path", req.URL.Path),
+				zap.Int("status", res.Status),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int64("bytesIn", req.ContentLength),
+				zap.Int64("bytesOut", res.Size),
+				zap.String("client", c.RealIP()),
+				zap.String("userAgent", req.UserAgent()),
+			}
+			if id, ok := auth.FromContext(req.Context()); ok {
+				fields = append(fields, zap.String("user", id.UserID))
+				if id.KeyID != "" {
+					fields = append(fields, zap.String("keyId", id.KeyID))
+				}
+			}
+			ce.Write(fields...)
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// New returns the logger configured by cfg and its level, which can be
+// changed while the logger is in use.
+func New(cfg *config.Log) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, level, fmt.Errorf("failed to parse log level: %w", err)
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "message",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	var encoder zapcore.Encoder
+	switch cfg.Encoding {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, level, fmt.Errorf("unknown log encoding %q", cfg.Encoding)
+	}
+
+	writers := make([]zapcore.WriteSyncer, 0, len(cfg.Outputs))
+	for _, out := range cfg.Outputs {
+		switch out {
+		case "stdout":
+			writers = append(writers, zapcore.Lock(os.Stdout))
+		case "stderr":
+			writers = append(writers, zapcore.Lock(os.Stderr))
+		default:
+			writers = append(writers, zapcore.AddSync(&lumberjack.Logger{
+				Filename:   out,
+				MaxSize:    cfg.Rotation.MaxSizeMB,
+				MaxAge:     int(time.Duration(cfg.Rotation.MaxAge) / (24 * time.Hour)),
+				MaxBackups: cfg.Rotation.MaxBackups,
+				Compress:   cfg.Rotation.Compress,
+			}))
+		}
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(writers...), level)
+	if cfg.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+	}
+
+	zlog := zap.New(core,
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
+
+	return zlog, level, nil
+}
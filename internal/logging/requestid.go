@@ -0,0 +1,81 @@
+// Package logging builds the logger, logs requests and correlates the logs
+// of a request.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLen bounds the request IDs accepted from clients, so they
+// cannot bloat every log line of their requests.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestID sets the ID of each request from its X-Request-ID header, or
+// generates one if the header is missing or invalid. The ID is echoed in
+// the response header and stored in the request context.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+
+			return next(c)
+		}
+	}
+}
+
+// RequestIDFromContext returns the request ID stored in ctx by RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// With returns zlog with the request ID and the trace ID of ctx, if any.
+func With(ctx context.Context, zlog *zap.Logger) *zap.Logger {
+	fields := make([]zap.Field, 0, 2)
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String("requestId", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, zap.String("traceId", sc.TraceID().String()))
+	}
+	if len(fields) == 0 {
+		return zlog
+	}
+	return zlog.With(fields...)
+}
+
+// validRequestID reports whether id is printable ASCII without spaces
+// and not longer than maxRequestIDLen.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,156 @@
+// Package metrics exposes the Prometheus metrics of the API.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/httperr"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "helpdesk"
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by route and status.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route", "status"})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of database queries, including reading their rows.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"query", "result"})
+
+	exportRows = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "export",
+		Name:      "rows",
+		Help:      "Number of tickets in generated exports.",
+		Buckets:   prometheus.ExponentialBuckets(10, 4, 8),
+	}, []string{"format"})
+
+	exportSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "export",
+		Name:      "size_bytes",
+		Help:      "Size of generated exports.",
+		Buckets:   prometheus.ExponentialBuckets(4<<10, 4, 9),
+	}, []string{"format"})
+
+	reportCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "report_cache",
+		Name:      "requests_total",
+		Help:      "Report requests by whether the cache served them (hit), they queried the database (miss) or waited for an identical request (shared).",
+	}, []string{"result"})
+
+	reportCacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "report_cache",
+		Name:      "evictions_total",
+		Help:      "Reports removed from the cache to make room.",
+	})
+
+	reportCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "report_cache",
+		Name:      "entries",
+		Help:      "Reports held by the cache, including expired ones not yet removed.",
+	})
+
+	openTickets = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "tickets",
+		Name:      "open",
+		Help:      "Number of tickets neither resolved, canceled nor rejected, by priority.",
+	}, []string{"priority"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Middleware records the duration of requests. Requests that match no
+// route are recorded under the route "unmatched" to bound the cardinality.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			code := c.Response().Status
+			if err != nil {
+				code = httperr.HTTPCode(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			requestDuration.
+				WithLabelValues(c.Request().Method, route, strconv.Itoa(code)).
+				Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// ObserveQuery records that the query name took d and failed if err is set.
+func ObserveQuery(name string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	queryDuration.WithLabelValues(name, result).Observe(d.Seconds())
+}
+
+// ObserveExport records an export of rows tickets in size bytes.
+func ObserveExport(format string, rows, size int) {
+	exportRows.WithLabelValues(format).Observe(float64(rows))
+	exportSize.WithLabelValues(format).Observe(float64(size))
+}
+
+// ObserveReportCache counts a report request by its result: hit, miss or
+// shared.
+func ObserveReportCache(result string) {
+	reportCacheRequests.WithLabelValues(result).Inc()
+}
+
+// AddReportCacheEvictions counts n reports evicted from the cache.
+func AddReportCacheEvictions(n int) {
+	reportCacheEvictions.Add(float64(n))
+}
+
+// SetReportCacheEntries sets the number of reports held by the cache.
+func SetReportCacheEntries(n int) {
+	reportCacheEntries.Set(float64(n))
+}
+
+// SetOpenTickets replaces the open ticket counts by priority.
+func SetOpenTickets(counts map[string]int64) {
+	openTickets.Reset()
+	for p, n := range counts {
+		openTickets.WithLabelValues(p).Set(float64(n))
+	}
+}
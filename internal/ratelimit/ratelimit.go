@@ -0,0 +1,175 @@
+// Package ratelimit limits the request rate of clients per route group.
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Route groups. The default limits of config.DefaultRateLimits are keyed
+// by these names.
+const (
+	GroupTickets = "tickets"
+	GroupReports = "reports"
+	GroupExports = "exports"
+	GroupAdmin   = "admin"
+)
+
+// Response headers, as in the IETF RateLimit header fields draft.
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+)
+
+// idleTimeout is how long the bucket of a client is kept after its last
+// request. A bucket left alone that long is full again anyway.
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter holds the token buckets of clients.
+type Limiter struct {
+	cfg *config.RateLimits
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// New returns a Limiter enforcing cfg.
+func New(cfg *config.RateLimits) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+}
+
+// ByIP limits requests by client IP. It runs before authentication, so it
+// bounds what anonymous clients can cost. The client IP is found by the
+// IPExtractor of echo, which must not trust headers set by clients.
+func (l *Limiter) ByIP() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := l.allow(c, "ip", "ip:"+c.RealIP(), l.cfg.IP); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// Group limits the requests to the routes of group per identity, or per
+// client IP for anonymous requests. It must run after authentication.
+//
+// The limit of the group is overridden by the limit the identity carries,
+// e.g. that of its API key, which is overridden in turn by the limits of
+// the identity in the config.
+func (l *Limiter) Group(group string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			client := "ip:" + c.RealIP()
+			limit := l.cfg.Groups[group]
+			if id, ok := auth.FromContext(c.Request().Context()); ok {
+				client = "user:" + id.UserID
+				if id.KeyID != "" {
+					client = "key:" + id.KeyID
+				}
+				if id.Limit != nil {
+					limit = *id.Limit
+				}
+			}
+			if o, ok := l.cfg.Identities[client][group]; ok {
+				limit = o
+			}
+
+			if err := l.allow(c, group, client, limit); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// allow takes a token from the bucket of client in group and sets the
+// RateLimit headers. It returns ResourceExhausted if the bucket is empty.
+func (l *Limiter) allow(c echo.Context, group, client string, limit config.Limit) error {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	key := group + "|" + client
+
+	l.mu.Lock()
+	if now.Sub(l.swept) >= idleTimeout {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) >= idleTimeout {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.buckets[key]
+	if !ok || b.limiter.Limit() != rate.Limit(limit.Rate) || b.limiter.Burst() != limit.Burst {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	allowed := b.limiter.AllowN(now, 1)
+	tokens := b.limiter.TokensAt(now)
+	l.mu.Unlock()
+
+	// Seconds until the bucket is full again.
+	reset := math.Ceil((float64(limit.Burst) - tokens) / limit.Rate)
+
+	h := c.Response().Header()
+	h.Set(HeaderLimit, strconv.Itoa(limit.Burst))
+	h.Set(HeaderRemaining, strconv.Itoa(max(0, int(tokens))))
+	h.Set(HeaderReset, strconv.Itoa(int(max(0, reset))))
+
+	if allowed {
+		return nil
+	}
+
+	retry := time.Duration(math.Ceil((1-tokens)/limit.Rate*1e3)) * time.Millisecond
+	h.Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+
+	s, _ := status.New(codes.ResourceExhausted, "Too many requests.").
+		WithDetails(
+			&edpb.ErrorInfo{
+				Reason: "RATE_LIMITED",
+				Domain: "helpdesk",
+				Metadata: map[string]string{
+					"group": group,
+					"limit": strconv.Itoa(limit.Burst),
+				},
+			},
+			&edpb.QuotaFailure{
+				Violations: []*edpb.QuotaFailure_Violation{
+					{
+						Subject:     client,
+						Description: "rate limit of " + group + " exceeded",
+					},
+				},
+			},
+			&edpb.RetryInfo{RetryDelay: durationpb.New(retry)},
+		)
+	return s.Err()
+}
@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/httperr"
+	"github.com/labstack/echo/v4"
+)
+
+// client sends requests from a peer address, optionally as an identity.
+type client struct {
+	remote string
+	xff    string
+	id     *auth.Identity
+}
+
+func newEcho(mw ...echo.MiddlewareFunc) *echo.Echo {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		c.NoContent(httperr.HTTPCode(err))
+	}
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, mw...)
+	return e
+}
+
+func (cl client) do(e *echo.Echo) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = cl.remote
+	if cl.xff != "" {
+		req.Header.Set(echo.HeaderXForwardedFor, cl.xff)
+	}
+	if cl.id != nil {
+		req = req.WithContext(auth.NewContext(req.Context(), cl.id))
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestByIP(t *testing.T) {
+	l := New(&config.RateLimits{IP: config.Limit{Rate: 0.001, Burst: 2}})
+	e := newEcho(l.ByIP())
+
+	// A client rotating X-Forwarded-For still shares the bucket of its
+	// peer address.
+	for i, xff := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		rec := client{remote: "203.0.113.7:5000", xff: xff}.do(e)
+
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+
+	if rec := (client{remote: "203.0.113.8:5000"}).do(e); rec.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	l := New(&config.RateLimits{IP: config.Limit{Rate: 0.5, Burst: 2}})
+	e := newEcho(l.ByIP())
+	cl := client{remote: "203.0.113.7:5000"}
+
+	rec := cl.do(e)
+	if got := rec.Header().Get(HeaderLimit); got != "2" {
+		t.Errorf("%s = %s, want 2", HeaderLimit, got)
+	}
+	if got := rec.Header().Get(HeaderRemaining); got != "1" {
+		t.Errorf("%s = %s, want 1", HeaderRemaining, got)
+	}
+	if got := rec.Header().Get(HeaderReset); got != "2" {
+		t.Errorf("%s = %s, want 2", HeaderReset, got)
+	}
+
+	cl.do(e)
+	rec = cl.do(e)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get(echo.HeaderRetryAfter); got != "2" {
+		t.Errorf("Retry-After = %s, want 2", got)
+	}
+	if got := rec.Header().Get(HeaderRemaining); got != "0" {
+		t.Errorf("%s = %s, want 0", HeaderRemaining, got)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	l := New(&config.RateLimits{
+		Groups: map[string]config.Limit{GroupExports: {Rate: 0.001, Burst: 1}},
+		Identities: map[string]map[string]config.Limit{
+			"user:1002": {GroupExports: {Rate: 0.001, Burst: 3}},
+		},
+	})
+	e := newEcho(l.Group(GroupExports))
+
+	tests := []struct {
+		name   string
+		client client
+		allow  int
+	}{
+		{name: "anonymous", client: client{remote: "203.0.113.7:5000"}, allow: 1},
+		{name: "user", client: client{remote: "203.0.113.7:5000", id: &auth.Identity{UserID: "1001"}}, allow: 1},
+		{name: "override", client: client{remote: "203.0.113.7:5000", id: &auth.Identity{UserID: "1002"}}, allow: 3},
+		{name: "api key", client: client{remote: "203.0.113.7:5000", id: &auth.Identity{UserID: "1001", KeyID: "k1"}}, allow: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.allow {
+				if rec := tt.client.do(e); rec.Code != http.StatusOK {
+					t.Fatalf("request %d: status = %d, want 200", i, rec.Code)
+				}
+			}
+			if rec := tt.client.do(e); rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("request %d: status = %d, want 429", tt.allow, rec.Code)
+			}
+		})
+	}
+}
+
+func TestGroupKeyLimit(t *testing.T) {
+	l := New(&config.RateLimits{
+		Groups: map[string]config.Limit{GroupTickets: {Rate: 0.001, Burst: 1}},
+		Identities: map[string]map[string]config.Limit{
+			"key:k2": {GroupTickets: {Rate: 0.001, Burst: 2}},
+		},
+	})
+	e := newEcho(l.Group(GroupTickets))
+	keyLimit := &config.Limit{Rate: 0.001, Burst: 4}
+
+	tests := []struct {
+		name  string
+		id    *auth.Identity
+		allow int
+	}{
+		{name: "key limit", id: &auth.Identity{UserID: "apikey:bi", KeyID: "k1", Limit: keyLimit}, allow: 4},
+		{name: "config overrides key limit", id: &auth.Identity{UserID: "apikey:etl", KeyID: "k2", Limit: keyLimit}, allow: 2},
+		{name: "no key limit", id: &auth.Identity{UserID: "apikey:ops", KeyID: "k3"}, allow: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := client{remote: "203.0.113.7:5000", id: tt.id}
+			for i := range tt.allow {
+				if rec := cl.do(e); rec.Code != http.StatusOK {
+					t.Fatalf("request %d: status = %d, want 200", i, rec.Code)
+				}
+			}
+
+			rec := cl.do(e)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("request %d: status = %d, want 429", tt.allow, rec.Code)
+			}
+			if rec.Header().Get(echo.HeaderRetryAfter) == "" || rec.Header().Get(HeaderLimit) == "" {
+				t.Errorf("headers = %v, want Retry-After and %s", rec.Header(), HeaderLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"github.com/robfig/cron/v3"
+)
+
+// Config configures scheduled report deliveries.
+type Config struct {
+	SMTP      SMTPConfig `json:"smtp"`
+	Retry     Retry      `json:"retry"`
+	Schedules []Schedule `json:"schedules"`
+
+	// HistoryFile is the JSON lines file deliveries are appended to.
+	// If empty, deliveries are not recorded.
+	HistoryFile string `json:"historyFile"`
+}
+
+// SMTPConfig configures the mail server reports are sent through.
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+
+	// TLS selects how the connection is secured: "starttls" upgrades the
+	// connection and fails if the server does not offer it, "implicit" dials TLS directly
+	// (port 465) and "none" sends in plain text, e.g. to a local stand-in.
+	TLS string `json:"tls"`
+
+	Timeout config.Duration `json:"timeout"`
+}
+
+// Retry configures how failed deliveries are retried.
+type Retry struct {
+	// Attempts is the total number of attempts, including the first one.
+	Attempts int `json:"attempts"`
+
+	// Backoff is the wait before the second attempt. It doubles after each
+	// failed attempt.
+	Backoff config.Duration `json:"backoff"`
+
+	// Timeout bounds each attempt, from generating the report to sending
+	// it. Defaults to 10 minutes.
+	Timeout config.Duration `json:"timeout"`
+}
+
+// Schedule is a report sent to recipients on a cron schedule.
+type Schedule struct {
+	Name string `json:"name"`
+
+	// Cron is a standard five field cron expression, e.g. "0 8 1 * *".
+	Cron string `json:"cron"`
+
+	// TimeZone is the IANA zone the cron expression and the report range
+	// are evaluated in. Defaults to helpdesk.DefaultTimeZone.
+	TimeZone string `json:"timeZone"`
+
+	// Range is the relative range of the report, e.g. lastMonth.
+	Range    string `json:"range"`
+	Template string `json:"template"`
+	Lang     string `json:"lang"`
+
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+	Recipients []string `json:"recipients"`
+}
+
+// LoadConfig reads the delivery config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule config: %w", err)
+	}
+
+	cfg := new(Config)
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config and fills in defaults.
+func (c *Config) Validate() error {
+	if c.SMTP.Host == "" {
+		return errors.New("smtp host is empty")
+	}
+	if c.SMTP.From == "" {
+		return errors.New("smtp from is empty")
+	}
+	if c.SMTP.Port == 0 {
+		c.SMTP.Port = 587
+	}
+	switch c.SMTP.TLS {
+	case "":
+		c.SMTP.TLS = "starttls"
+	case "starttls", "implicit", "none":
+	default:
+		return fmt.Errorf("smtp tls %q must be starttls, implicit or none", c.SMTP.TLS)
+	}
+	if c.SMTP.Timeout == 0 {
+		c.SMTP.Timeout = config.Duration(30 * time.Second)
+	}
+
+	if c.Retry.Attempts <= 0 {
+		c.Retry.Attempts = 3
+	}
+	if c.Retry.Backoff == 0 {
+		c.Retry.Backoff = config.Duration(time.Minute)
+	}
+	if c.Retry.Timeout <= 0 {
+		c.Retry.Timeout = config.Duration(10 * time.Minute)
+	}
+
+	names := make(map[string]bool, len(c.Schedules))
+	for i := range c.Schedules {
+		s := &c.Schedules[i]
+		if s.Name == "" {
+			return fmt.Errorf("schedule %d: name is empty", i+1)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("schedule %q: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.TimeZone == "" {
+			s.TimeZone = helpdesk.DefaultTimeZone
+		}
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return fmt.Errorf("schedule %q: %w", s.Name, err)
+		}
+		if _, err := cron.ParseStandard(s.spec()); err != nil {
+			return fmt.Errorf("schedule %q: invalid cron: %w", s.Name, err)
+		}
+		switch s.Range {
+		case helpdesk.RangeToday,
+			helpdesk.RangeLast7Days,
+			helpdesk.RangeThisMonth,
+			helpdesk.RangeLastMonth,
+			helpdesk.RangeThisQuarter,
+			helpdesk.RangeYearToDate:
+		default:
+			return fmt.Errorf("schedule %q: unknown range %q", s.Name, s.Range)
+		}
+		if len(s.Recipients) == 0 {
+			return fmt.Errorf("schedule %q: no recipients", s.Name)
+		}
+		if s.Subject == "" {
+			s.Subject = "Helpdesk report " + s.Name
+		}
+	}
+
+	return nil
+}
+
+// spec returns the cron expression evaluated in the schedule's time zone.
+func (s *Schedule) spec() string {
+	return fmt.Sprintf("CRON_TZ=%s %s", s.TimeZone, s.Cron)
+}
@@ -0,0 +1,41 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/jsonl"
+)
+
+// Delivery records an attempt to send a scheduled report.
+type Delivery struct {
+	Schedule   string    `json:"schedule"`
+	Attempt    int       `json:"attempt"`
+	Recipients []string  `json:"recipients"`
+	Bytes      int       `json:"bytes"`
+	Status     string    `json:"status"` // SENT, FAILED
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// History appends deliveries to a JSON lines file.
+// It is safe for concurrent use.
+type History struct {
+	file *jsonl.Appender
+}
+
+func NewHistory(path string) *History {
+	return &History{file: jsonl.NewAppender(path)}
+}
+
+// Append records d. It does nothing if the history has no file.
+func (h *History) Append(d *Delivery) error {
+	if h == nil {
+		return nil
+	}
+	if err := h.file.Append(d); err != nil {
+		return fmt.Errorf("failed to append history: %w", err)
+	}
+	return nil
+}
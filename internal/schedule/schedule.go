@@ -0,0 +1,198 @@
+// Package schedule sends helpdesk reports by email on cron schedules.
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// ReportGenerator generates the Excel workbook of a ticket export.
+type ReportGenerator interface {
+	GenExcel(ctx context.Context, in *helpdesk.BatchGetTicketsQuery) (*bytes.Buffer, error)
+}
+
+// Scheduler runs the configured schedules.
+type Scheduler struct {
+	cfg     *Config
+	gen     ReportGenerator
+	sender  Sender
+	history *History
+	cron    *cron.Cron
+	zlog    *zap.Logger
+
+	// ctx is the parent of scheduled runs; Stop cancels it.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewScheduler(cfg *Config, gen ReportGenerator, sender Sender, zlog *zap.Logger) (*Scheduler, error) {
+	if cfg == nil {
+		return nil, errors.New("cfg is nil")
+	}
+	if gen == nil {
+		return nil, errors.New("gen is nil")
+	}
+	if sender == nil {
+		return nil, errors.New("sender is nil")
+	}
+	if zlog == nil {
+		return nil, errors.New("zlog is nil")
+	}
+
+	s := &Scheduler{
+		cfg:     cfg,
+		gen:     gen,
+		sender:  sender,
+		history: NewHistory(cfg.HistoryFile),
+		cron:    cron.New(),
+		zlog:    zlog.With(zap.String("component", "schedule")),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	for _, sc := range cfg.Schedules {
+		name := sc.Name
+		_, err := s.cron.AddFunc(sc.spec(), func() {
+			if err := s.Run(s.ctx, name); err != nil {
+				s.zlog.Error("failed to deliver scheduled report", zap.String("schedule", name), zap.Error(err))
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add schedule %q: %w", name, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Start runs the schedules in the background.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops the schedules and cancels running deliveries. The returned
+// context is done once they have returned.
+func (s *Scheduler) Stop() context.Context {
+	s.cancel()
+	return s.cron.Stop()
+}
+
+// Run generates and sends the report of the named schedule now, retrying
+// failed attempts with backoff. Each attempt is bounded by the retry
+// timeout, and Run returns early once ctx is done.
+func (s *Scheduler) Run(ctx context.Context, name string) error {
+	var sc *Schedule
+	for i := range s.cfg.Schedules {
+		if s.cfg.Schedules[i].Name == name {
+			sc = &s.cfg.Schedules[i]
+			break
+		}
+	}
+	if sc == nil {
+		return fmt.Errorf("unknown schedule %q", name)
+	}
+
+	zlog := s.zlog.With(zap.String("schedule", name))
+	zlog.Info("starting to deliver scheduled report")
+
+	backoff := time.Duration(s.cfg.Retry.Backoff)
+	var err error
+	for attempt := 1; attempt <= s.cfg.Retry.Attempts; attempt++ {
+		if attempt > 1 {
+			zlog.Warn("retrying scheduled report", zap.Int("attempt", attempt), zap.Duration("backoff", backoff))
+			if werr := wait(ctx, backoff); werr != nil {
+				return errors.Join(err, werr)
+			}
+			backoff *= 2
+		}
+
+		d := &Delivery{
+			Schedule:   name,
+			Attempt:    attempt,
+			Recipients: sc.Recipients,
+			StartedAt:  time.Now(),
+		}
+		d.Bytes, err = s.attempt(ctx, sc)
+		d.FinishedAt = time.Now()
+		d.Status = "SENT"
+		if err != nil {
+			d.Status = "FAILED"
+			d.Error = err.Error()
+		}
+		if herr := s.history.Append(d); herr != nil {
+			zlog.Error("failed to record delivery", zap.Error(herr))
+		}
+
+		if err == nil {
+			zlog.Info("scheduled report sent", zap.Int("attempt", attempt), zap.Int("bytes", d.Bytes))
+			return nil
+		}
+		zlog.Error("failed to send scheduled report", zap.Int("attempt", attempt), zap.Error(err))
+	}
+
+	return err
+}
+
+// wait waits for d or until ctx is done.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (s *Scheduler) attempt(ctx context.Context, sc *Schedule) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.cfg.Retry.Timeout))
+	defer cancel()
+
+	return s.deliver(ctx, sc)
+}
+
+func (s *Scheduler) deliver(ctx context.Context, sc *Schedule) (int, error) {
+	ctx = i18n.NewContext(ctx, i18n.Negotiate(sc.Lang, ""))
+
+	in := &helpdesk.BatchGetTicketsQuery{
+		Template: sc.Template,
+	}
+	in.Range = sc.Range
+	in.TimeZone = sc.TimeZone
+
+	buf, err := s.gen.GenExcel(ctx, in)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate report: %w", err)
+	}
+
+	loc, _ := time.LoadLocation(sc.TimeZone)
+	filename := fmt.Sprintf("help-desk-tickets-%s-%s.xlsx", sc.Name, time.Now().In(loc).Format("2006-01-02"))
+
+	msg := &Message{
+		From:    s.cfg.SMTP.From,
+		To:      sc.Recipients,
+		Subject: sc.Subject,
+		Body:    sc.Body,
+		Attachments: []Attachment{
+			{
+				Filename:    filename,
+				ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				Data:        buf.Bytes(),
+			},
+		},
+	}
+	if err := s.sender.Send(ctx, msg); err != nil {
+		return 0, err
+	}
+
+	return buf.Len(), nil
+}
@@ -0,0 +1,299 @@
+package schedule
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"go.uber.org/zap"
+)
+
+// smtpServer is an in-process SMTP server that records the messages it
+// accepts. It rejects the data of the first failures messages.
+type smtpServer struct {
+	ln       net.Listener
+	starttls bool
+
+	mu       sync.Mutex
+	failures int
+	messages []string
+}
+
+func newSMTPServer(t *testing.T, failures int, starttls bool) *smtpServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln, failures: failures, starttls: starttls}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			if s.starttls {
+				reply("250-localhost")
+				reply("250 STARTTLS")
+			} else {
+				reply("250 localhost")
+			}
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 Go ahead")
+			var msg strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				msg.WriteString(l)
+			}
+
+			s.mu.Lock()
+			if s.failures > 0 {
+				s.failures--
+				s.mu.Unlock()
+				reply("451 Try again later")
+				continue
+			}
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 Queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+type fakeGenerator struct{}
+
+func (fakeGenerator) GenExcel(context.Context, *helpdesk.BatchGetTicketsQuery) (*bytes.Buffer, error) {
+	return bytes.NewBufferString("workbook"), nil
+}
+
+func newTestConfig(t *testing.T, port int, tls string) *Config {
+	t.Helper()
+
+	cfg := &Config{
+		SMTP: SMTPConfig{
+			Host: "127.0.0.1",
+			Port: port,
+			From: "helpdesk@example.com",
+			TLS:  tls,
+		},
+		Retry: Retry{
+			Attempts: 3,
+			Backoff:  config.Duration(time.Millisecond),
+		},
+		HistoryFile: filepath.Join(t.TempDir(), "deliveries.jsonl"),
+		Schedules: []Schedule{
+			{
+				Name:       "monthly",
+				Cron:       "0 8 1 * *",
+				Range:      helpdesk.RangeLastMonth,
+				Recipients: []string{"it@example.com"},
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func readHistory(t *testing.T, path string) []*Delivery {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deliveries []*Delivery
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for dec.More() {
+		d := new(Delivery)
+		if err := dec.Decode(d); err != nil {
+			t.Fatal(err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+func TestSchedulerRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		starttls bool
+		tls      string
+		wantErr  bool
+		wantSent int
+		statuses []string
+	}{
+		{
+			name:     "sent",
+			tls:      "none",
+			wantSent: 1,
+			statuses: []string{"SENT"},
+		},
+		{
+			name:     "retried",
+			failures: 2,
+			tls:      "none",
+			wantSent: 1,
+			statuses: []string{"FAILED", "FAILED", "SENT"},
+		},
+		{
+			name:     "attempts exhausted",
+			failures: 3,
+			tls:      "none",
+			wantErr:  true,
+			statuses: []string{"FAILED", "FAILED", "FAILED"},
+		},
+		{
+			name:     "starttls not offered",
+			tls:      "starttls",
+			wantErr:  true,
+			statuses: []string{"FAILED", "FAILED", "FAILED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSMTPServer(t, tt.failures, tt.starttls)
+			cfg := newTestConfig(t, srv.port(), tt.tls)
+
+			s, err := NewScheduler(cfg, fakeGenerator{}, NewSMTPSender(cfg.SMTP), zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Run(context.Background(), "monthly")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			msgs := srv.received()
+			if len(msgs) != tt.wantSent {
+				t.Fatalf("received %d messages, want %d", len(msgs), tt.wantSent)
+			}
+			for _, msg := range msgs {
+				if !strings.Contains(msg, "To: it@example.com\r\n") {
+					t.Errorf("message has no recipient header:\n%s", msg)
+				}
+				if !strings.Contains(msg, "help-desk-tickets-monthly-") {
+					t.Errorf("message has no attachment:\n%s", msg)
+				}
+			}
+
+			history := readHistory(t, cfg.HistoryFile)
+			if len(history) != len(tt.statuses) {
+				t.Fatalf("history has %d deliveries, want %d", len(history), len(tt.statuses))
+			}
+			for i, d := range history {
+				if d.Attempt != i+1 || d.Status != tt.statuses[i] {
+					t.Errorf("delivery %d = attempt %d %s, want attempt %d %s", i, d.Attempt, d.Status, i+1, tt.statuses[i])
+				}
+				if d.Status == "SENT" && d.Bytes != len("workbook") {
+					t.Errorf("delivery %d bytes = %d, want %d", i, d.Bytes, len("workbook"))
+				}
+				if d.Status == "FAILED" && d.Error == "" {
+					t.Errorf("delivery %d failed without an error", i)
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulerStopCancelsBackoff(t *testing.T) {
+	srv := newSMTPServer(t, 1, false)
+	cfg := newTestConfig(t, srv.port(), "none")
+	cfg.Retry.Backoff = config.Duration(time.Hour)
+
+	s, err := NewScheduler(cfg, fakeGenerator{}, NewSMTPSender(cfg.SMTP), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run(s.ctx, "monthly") }()
+
+	// Wait for the first attempt to be recorded before stopping.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if b, _ := os.ReadFile(cfg.HistoryFile); bytes.HasSuffix(b, []byte("\n")) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first attempt was not recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Stop()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after Stop")
+	}
+}
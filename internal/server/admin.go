@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/apikey"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/audit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func (s *Server) getReportCache(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.hdSvc.ReportCacheStats())
+}
+
+func (s *Server) purgeReportCache(c echo.Context) error {
+	n := s.hdSvc.PurgeReportCache()
+	zap.L().Info("purged report cache", zap.Int("entries", n))
+
+	return c.JSON(http.StatusOK, echo.Map{"purged": n})
+}
+
+func (s *Server) listAPIKeys(c echo.Context) error {
+	keys, err := s.apiKeys.List()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"keys": keys})
+}
+
+func (s *Server) mintAPIKey(c echo.Context) error {
+	req := new(apikey.MintRequest)
+	if err := c.Bind(req); err != nil {
+		return badBind(err)
+	}
+
+	key, token, err := s.apiKeys.Mint(req)
+	if err != nil {
+		return err
+	}
+
+	actor, _ := auth.FromContext(c.Request().Context())
+	zap.L().Info("minted api key", zap.String("keyId", key.ID), zap.String("name", key.Name), zap.String("by", actor.UserID))
+
+	return c.JSON(http.StatusCreated, echo.Map{"key": key, "token": token})
+}
+
+func (s *Server) revokeAPIKey(c echo.Context) error {
+	key, err := s.apiKeys.Revoke(c.Param("id"))
+	if errors.Is(err, apikey.ErrKeyNotFound) {
+		return status.Error(codes.NotFound, "API key does not exist.")
+	}
+	if err != nil {
+		return err
+	}
+
+	actor, _ := auth.FromContext(c.Request().Context())
+	zap.L().Info("revoked api key", zap.String("keyId", key.ID), zap.String("name", key.Name), zap.String("by", actor.UserID))
+
+	return c.JSON(http.StatusOK, echo.Map{"key": key})
+}
+
+func (s *Server) queryAuditLog(c echo.Context) error {
+	req := new(audit.Filter)
+	if err := c.Bind(req); err != nil {
+		return badBind(err)
+	}
+
+	entries, err := s.auditLog.Query(req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"entries": entries})
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+func (s *Server) getLogLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"level": s.logLevel.Level().String()})
+}
+
+func (s *Server) setLogLevel(c echo.Context) error {
+	req := new(logLevelRequest)
+	if err := c.Bind(req); err != nil {
+		return badBind(err)
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil || req.Level == "" {
+		st, _ := status.New(codes.InvalidArgument, "Log level is not valid.").
+			WithDetails(&edpb.BadRequest{
+				FieldViolations: []*edpb.BadRequest_FieldViolation{
+					{
+						Field:       "level",
+						Description: "level must be debug, info, warn, error, dpanic, panic or fatal",
+					},
+				},
+			})
+		return st.Err()
+	}
+
+	// Logged before the change, so raising the level does not hide it.
+	actor, _ := auth.FromContext(c.Request().Context())
+	zap.L().Warn("changing log level", zap.Stringer("from", s.logLevel.Level()), zap.Stringer("to", level), zap.String("by", actor.UserID))
+	s.logLevel.SetLevel(level)
+
+	return c.JSON(http.StatusOK, echo.Map{"level": level.String()})
+}
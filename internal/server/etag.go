@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultCacheControl is sent by routes without a configured Cache-Control.
+// Responses hold employee data, so shared caches must not store them, but
+// clients may revalidate them with their ETag.
+const defaultCacheControl = "private, no-cache"
+
+// etag returns a strong entity tag of the payload.
+func etag(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:18]) + `"`
+}
+
+// etagMatch reports whether the If-None-Match header matches tag.
+// Weak validators match as well, as RFC 9110 requires for GET.
+func etagMatch(header, tag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadCacheControl reads the Cache-Control header of routes from a JSON file
+// of the form
+//
+//	{"/v1/helpdesk/tickets": "private, max-age=30"}
+func LoadCacheControl(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache control: %w", err)
+	}
+
+	var routes map[string]string
+	if err := json.Unmarshal(b, &routes); err != nil {
+		return nil, fmt.Errorf("failed to parse cache control: %w", err)
+	}
+
+	return routes, nil
+}
+
+// blob sends b with an ETag and the Cache-Control of the route. It sends
+// 304 Not Modified without the body if the client holds the same payload.
+// The ETag is a hash of the generated payload, so a 304 saves bandwidth
+// but not the queries and rendering that produced b; exports hash the same
+// only because they are generated deterministically.
+func (s *Server) blob(c echo.Context, contentType string, b []byte) error {
+	h := c.Response().Header()
+
+	cc, ok := s.cacheControl[c.Path()]
+	if !ok {
+		cc = defaultCacheControl
+	}
+	h.Set(echo.HeaderCacheControl, cc)
+
+	tag := etag(b)
+	h.Set("ETag", tag)
+	h.Add(echo.HeaderVary, "Accept-Language")
+	h.Add(echo.HeaderVary, echo.HeaderAuthorization)
+	h.Add(echo.HeaderVary, auth.HeaderAPIKey)
+
+	if inm := c.Request().Header.Get("If-None-Match"); inm != "" && etagMatch(inm, tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, contentType, b)
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestETagMatch(t *testing.T) {
+	tag := etag([]byte("payload"))
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "same tag", header: tag, want: true},
+		{name: "weak tag", header: "W/" + tag, want: true},
+		{name: "any", header: "*", want: true},
+		{name: "in list", header: `"other", ` + tag, want: true},
+		{name: "in list without spaces", header: `"other",` + tag + `,"third"`, want: true},
+		{name: "other tag", header: etag([]byte("other")), want: false},
+		{name: "unquoted tag", header: tag[1 : len(tag)-1], want: false},
+		{name: "empty", header: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatch(tt.header, tag); got != tt.want {
+				t.Errorf("etagMatch(%q, %q) = %v, want %v", tt.header, tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	if etag([]byte("payload")) != etag([]byte("payload")) {
+		t.Error("etag of the same payload differs")
+	}
+	if etag([]byte("payload")) == etag([]byte("payloads")) {
+		t.Error("etag of different payloads is the same")
+	}
+}
+
+func TestBlob(t *testing.T) {
+	s := &Server{cacheControl: map[string]string{"/v1/reports": "private, max-age=60"}}
+	e := echo.New()
+
+	serve := func(path, inm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath(path)
+		if err := s.blob(c, echo.MIMEApplicationJSON, []byte(`{"a":1}`)); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	rec := serve("/v1/tickets", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"a":1}` {
+		t.Fatalf("response = %d %q, want 200 and the body", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get(echo.HeaderCacheControl); got != defaultCacheControl {
+		t.Errorf("Cache-Control = %q, want %q", got, defaultCacheControl)
+	}
+	// Credentials of either kind select what the response holds.
+	for _, h := range []string{"Accept-Language", echo.HeaderAuthorization, "X-API-Key"} {
+		if !slices.Contains(rec.Header().Values(echo.HeaderVary), h) {
+			t.Errorf("Vary = %v, want %s", rec.Header().Values(echo.HeaderVary), h)
+		}
+	}
+
+	if got := serve("/v1/reports", "").Header().Get(echo.HeaderCacheControl); got != "private, max-age=60" {
+		t.Errorf("Cache-Control = %q, want the configured one", got)
+	}
+
+	rec = serve("/v1/tickets", rec.Header().Get("ETag"))
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want 304 without a body", rec.Code, rec.Body)
+	}
+}
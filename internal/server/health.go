@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// poolStats is sql.DBStats with JSON names.
+type poolStats struct {
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDuration       string `json:"waitDuration"`
+	MaxIdleClosed      int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64  `json:"maxLifetimeClosed"`
+}
+
+func newPoolStats(s sql.DBStats) *poolStats {
+	return &poolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.String(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// healthz reports that the process serves requests.
+func (s *Server) healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"status": "ok"})
+}
+
+// readyz reports whether the database can serve requests.
+func (s *Server) readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), s.readiness.Timeout)
+	defer cancel()
+
+	if err := s.hdSvc.Ready(ctx, s.readiness.CheckView); err != nil {
+		zap.L().Warn("not ready", zap.Error(err))
+
+		st, _ := status.New(codes.Unavailable, "Database is unavailable.").
+			WithDetails(
+				&edpb.ErrorInfo{
+					Reason: "DB_UNAVAILABLE",
+					Domain: "helpdesk",
+				},
+				&edpb.RetryInfo{RetryDelay: durationpb.New(5 * time.Second)},
+			)
+		return st.Err()
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"status": "ok",
+		"db":     newPoolStats(s.hdSvc.DBStats()),
+	})
+}
+
+func (s *Server) getDBStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, newPoolStats(s.hdSvc.DBStats()))
+}
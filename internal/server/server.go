@@ -1,10 +1,17 @@
 package server
 
 import (
+	"encoding/json"
 	"errors"
-	"net/http"
+	"slices"
+	"time"
 
+	"github.com/10664kls/helpdesk-dashboad-api/internal/apikey"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/audit"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/auth"
 	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/i18n"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/ratelimit"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -14,16 +21,89 @@ import (
 )
 
 type Server struct {
-	hdSvc *helpdesk.Service
+	hdSvc        *helpdesk.Service
+	watcher      *helpdesk.Watcher
+	cacheControl map[string]string
+	apiKeys      *apikey.Store
+	auditLog     *audit.Log
+	limits       *ratelimit.Limiter
+	readiness    Readiness
+	logLevel     *zap.AtomicLevel
 }
 
-func NewServer(helpdesk *helpdesk.Service) (*Server, error) {
+// Readiness configures the checks of /readyz.
+type Readiness struct {
+	Timeout   time.Duration
+	CheckView bool
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithWatcher streams the events of w to clients of the ticket stream.
+// Without a watcher the stream is unavailable.
+func WithWatcher(w *helpdesk.Watcher) Option {
+	return func(s *Server) {
+		s.watcher = w
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of routes, keyed by route
+// path such as /v1/helpdesk/tickets. Other routes send "private, no-cache".
+func WithCacheControl(routes map[string]string) Option {
+	return func(s *Server) {
+		s.cacheControl = routes
+	}
+}
+
+// WithAPIKeys serves the admin API managing the keys of store.
+func WithAPIKeys(store *apikey.Store) Option {
+	return func(s *Server) {
+		s.apiKeys = store
+	}
+}
+
+// WithAuditLog records ticket views, exports and reports to log and serves
+// the admin API querying it.
+func WithAuditLog(log *audit.Log) Option {
+	return func(s *Server) {
+		s.auditLog = log
+	}
+}
+
+// WithRateLimits limits the requests to each route group per identity.
+func WithRateLimits(l *ratelimit.Limiter) Option {
+	return func(s *Server) {
+		s.limits = l
+	}
+}
+
+// WithReadiness sets the checks of /readyz. By default the database is
+// pinged with a timeout of two seconds.
+func WithReadiness(r Readiness) Option {
+	return func(s *Server) {
+		s.readiness = r
+	}
+}
+
+// WithLogLevel lets admins read and change level at runtime.
+func WithLogLevel(level zap.AtomicLevel) Option {
+	return func(s *Server) {
+		s.logLevel = &level
+	}
+}
+
+func NewServer(helpdesk *helpdesk.Service, opts ...Option) (*Server, error) {
 	if helpdesk == nil {
 		return nil, errors.New("helpdesk service is nil")
 	}
 
 	s := &Server{
-		hdSvc: helpdesk,
+		hdSvc:     helpdesk,
+		readiness: Readiness{Timeout: 2 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	return s, nil
 }
@@ -33,15 +113,79 @@ func (s *Server) Install(e *echo.Echo, mdw ...echo.MiddlewareFunc) error {
 		return errors.New("echo is nil")
 	}
 
-	v1 := e.Group("/v1")
+	// Probes are neither authenticated nor localized.
+	e.GET("/healthz", s.healthz)
+	e.GET("/readyz", s.readyz)
+
+	v1 := e.Group("/v1", negotiateLanguage)
+
+	// with returns mdw followed by more.
+	with := func(more ...echo.MiddlewareFunc) []echo.MiddlewareFunc {
+		return append(slices.Clone(mdw), more...)
+	}
+	// route returns the middleware of a route group: the call is audited,
+	// including calls denied by scope or rate, then limited and authorized.
+	route := func(group, scope string, audited bool) []echo.MiddlewareFunc {
+		var more []echo.MiddlewareFunc
+		if audited && s.auditLog != nil {
+			more = append(more, audit.Middleware(s.auditLog, zap.L()))
+		}
+		if s.limits != nil {
+			more = append(more, s.limits.Group(group))
+		}
+		if scope != "" {
+			more = append(more, auth.RequireScope(scope))
+		}
+		return with(more...)
+	}
+	tickets := route(ratelimit.GroupTickets, auth.ScopeTicketsRead, true)
+	exports := route(ratelimit.GroupExports, auth.ScopeExportsCreate, true)
+	reports := route(ratelimit.GroupReports, auth.ScopeReportsRead, true)
+	stream := route(ratelimit.GroupTickets, auth.ScopeTicketsRead, false)
 
 	hd := v1.Group("/helpdesk")
-	hd.GET("/tickets", s.listTickets, mdw...)
-	hd.GET("/tickets/export-to-excel", s.exportToExcel, mdw...)
+	hd.GET("/tickets", s.listTickets, tickets...)
+	hd.GET("/tickets/export-to-excel", s.exportToExcel, exports...)
+	hd.GET("/tickets/export-to-csv", s.exportToCSV, exports...)
+	hd.GET("/tickets/export-to-pdf", s.exportToPDF, reports...)
+	hd.GET("/tickets/stream", s.streamTickets, stream...)
+	hd.GET("/tickets/stream/ws", s.streamTicketsWS, stream...)
+
+	// Admin routes need an admin identity, so they are closed when
+	// authentication is not configured.
+	adminMdw := append(route(ratelimit.GroupAdmin, "", false), auth.RequireRole(auth.RoleAdmin))
+
+	admin := v1.Group("/admin")
+	admin.GET("/report-cache", s.getReportCache, adminMdw...)
+	admin.DELETE("/report-cache", s.purgeReportCache, adminMdw...)
+	admin.GET("/db-stats", s.getDBStats, adminMdw...)
+	if s.logLevel != nil {
+		admin.GET("/log-level", s.getLogLevel, adminMdw...)
+		admin.PUT("/log-level", s.setLogLevel, adminMdw...)
+	}
+	if s.apiKeys != nil {
+		admin.GET("/api-keys", s.listAPIKeys, adminMdw...)
+		admin.POST("/api-keys", s.mintAPIKey, adminMdw...)
+		admin.DELETE("/api-keys/:id", s.revokeAPIKey, adminMdw...)
+	}
+	if s.auditLog != nil {
+		admin.GET("/audit", s.queryAuditLog, adminMdw...)
+	}
 
 	return nil
 }
 
+// negotiateLanguage stores the language requested with the lang query
+// parameter or the Accept-Language header in the request context.
+func negotiateLanguage(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		tag := i18n.Negotiate(c.QueryParam("lang"), req.Header.Get("Accept-Language"))
+		c.SetRequest(req.WithContext(i18n.NewContext(req.Context(), tag)))
+		return next(c)
+	}
+}
+
 // badJSON is a helper function to create an error when c.Bind return an error.
 func badJSON() error {
 	s, _ := status.New(codes.InvalidArgument, "Request body must be a valid JSON.").
@@ -53,10 +197,24 @@ func badJSON() error {
 	return s.Err()
 }
 
+// badBind is a helper function to create an error when c.Bind return an error.
+// It returns the status of a field that failed to parse, if there is one,
+// otherwise it falls back to badJSON.
+func badBind(err error) error {
+	var he *echo.HTTPError
+	if errors.As(err, &he) && he.Internal != nil {
+		if s, ok := status.FromError(he.Internal); ok {
+			zap.L().Error("failed to bind request", zap.Error(err))
+			return s.Err()
+		}
+	}
+	return badJSON()
+}
+
 func (s *Server) listTickets(c echo.Context) error {
 	req := new(helpdesk.TicketQuery)
 	if err := c.Bind(req); err != nil {
-		return badJSON()
+		return badBind(err)
 	}
 
 	ctx := c.Request().Context()
@@ -65,13 +223,18 @@ func (s *Server) listTickets(c echo.Context) error {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, tickets)
+	b, err := json.Marshal(tickets)
+	if err != nil {
+		return err
+	}
+
+	return s.blob(c, echo.MIMEApplicationJSON, b)
 }
 
 func (s *Server) exportToExcel(c echo.Context) error {
 	req := new(helpdesk.BatchGetTicketsQuery)
 	if err := c.Bind(req); err != nil {
-		return badJSON()
+		return badBind(err)
 	}
 
 	ctx := c.Request().Context()
@@ -80,8 +243,41 @@ func (s *Server) exportToExcel(c echo.Context) error {
 		return err
 	}
 
-	c.Response().Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"help-desk-tickets.xlsx\"")
 
-	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+	return s.blob(c, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+}
+
+func (s *Server) exportToCSV(c echo.Context) error {
+	req := new(helpdesk.BatchGetTicketsQuery)
+	if err := c.Bind(req); err != nil {
+		return badBind(err)
+	}
+
+	ctx := c.Request().Context()
+	buf, err := s.hdSvc.GenCSV(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"help-desk-tickets.csv\"")
+
+	return s.blob(c, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+func (s *Server) exportToPDF(c echo.Context) error {
+	req := new(helpdesk.ReportQuery)
+	if err := c.Bind(req); err != nil {
+		return badBind(err)
+	}
+
+	ctx := c.Request().Context()
+	buf, err := s.hdSvc.GenPDF(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"help-desk-summary.pdf\"")
+
+	return s.blob(c, "application/pdf", buf.Bytes())
 }
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// heartbeat is the interval of the comments keeping idle streams open
+// through proxies.
+const heartbeat = 25 * time.Second
+
+// watch binds the ticket filter of the request and subscribes to the
+// matching events until the request is done.
+func (s *Server) watch(c echo.Context) (<-chan *helpdesk.TicketEvent, error) {
+	if s.watcher == nil {
+		return nil, status.Error(codes.Unavailable, "Ticket stream is not enabled.")
+	}
+
+	req := new(helpdesk.TicketQuery)
+	if err := c.Bind(req); err != nil {
+		return nil, badBind(err)
+	}
+
+	return s.watcher.Watch(c.Request().Context(), req)
+}
+
+// streamTickets sends the ticket events matching the query as
+// Server-Sent Events.
+func (s *Server) streamTickets(c echo.Context) error {
+	events, err := s.watch(c)
+	if err != nil {
+		return err
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	w.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	var id int
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				zap.L().Error("failed to marshal ticket event", zap.Error(err))
+				continue
+			}
+
+			id++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, e.Type, data); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+	}
+}
+
+// streamTicketsWS sends the ticket events matching the query as JSON
+// messages over a WebSocket.
+func (s *Server) streamTicketsWS(c echo.Context) error {
+	events, err := s.watch(c)
+	if err != nil {
+		return err
+	}
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		// The client sends nothing, so a read returns once it goes away.
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var msg []byte
+			for websocket.Message.Receive(ws, &msg) == nil {
+			}
+		}()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return
+
+			case <-ticker.C:
+				if err := websocket.Message.Send(ws, `{"type":"ping"}`); err != nil {
+					return
+				}
+
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, e); err != nil {
+					zap.L().Debug("failed to send ticket event", zap.String("ticketId", e.Ticket.ID), zap.Error(err))
+					return
+				}
+			}
+		}
+	}).ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/config"
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+)
+
+// Config configures webhook notifications.
+type Config struct {
+	Endpoints []Endpoint `json:"endpoints"`
+	Retry     Retry      `json:"retry"`
+
+	// Timeout bounds each delivery attempt.
+	Timeout config.Duration `json:"timeout"`
+
+	// DeliveryLog is the JSON lines file attempts are appended to.
+	// If empty, deliveries are not logged.
+	DeliveryLog string `json:"deliveryLog"`
+}
+
+// Endpoint receives the events matching any of its rules.
+type Endpoint struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Secret signs the payloads with HMAC-SHA256.
+	Secret string `json:"secret"`
+
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches ticket events. Empty fields match anything.
+type Rule struct {
+	// Event is ticket.created or ticket.updated.
+	Event string `json:"event"`
+
+	// Priority is HIGH, MEDIUM or LOW.
+	Priority string `json:"priority"`
+
+	// Status is the status of the ticket after the event, e.g. REJECTED.
+	Status string `json:"status"`
+
+	Category string `json:"category"`
+}
+
+// Retry configures how failed deliveries are retried.
+type Retry struct {
+	// Attempts is the total number of attempts, including the first one.
+	Attempts int `json:"attempts"`
+
+	// Backoff is the wait before the second attempt. It doubles after each
+	// failed attempt up to MaxBackoff.
+	Backoff    config.Duration `json:"backoff"`
+	MaxBackoff config.Duration `json:"maxBackoff"`
+}
+
+// LoadConfig reads the webhook config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook config: %w", err)
+	}
+
+	cfg := new(Config)
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid webhook config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config and fills in defaults.
+func (c *Config) Validate() error {
+	if c.Timeout == 0 {
+		c.Timeout = config.Duration(10 * time.Second)
+	}
+	if c.Retry.Attempts <= 0 {
+		c.Retry.Attempts = 5
+	}
+	if c.Retry.Backoff == 0 {
+		c.Retry.Backoff = config.Duration(5 * time.Second)
+	}
+	if c.Retry.MaxBackoff == 0 {
+		c.Retry.MaxBackoff = config.Duration(5 * time.Minute)
+	}
+
+	for i, e := range c.Endpoints {
+		if e.Name == "" {
+			return fmt.Errorf("endpoint %d: name is empty", i+1)
+		}
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("endpoint %q: url must be an http or https URL", e.Name)
+		}
+		if e.Secret == "" {
+			return fmt.Errorf("endpoint %q: secret is empty", e.Name)
+		}
+		if len(e.Rules) == 0 {
+			return fmt.Errorf("endpoint %q: no rules", e.Name)
+		}
+		for j, r := range e.Rules {
+			switch r.Event {
+			case "", helpdesk.EventTicketCreated, helpdesk.EventTicketUpdated:
+			default:
+				return fmt.Errorf("endpoint %q: rule %d: unknown event %q", e.Name, j+1, r.Event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Match reports whether the rule matches e.
+func (r *Rule) Match(e *helpdesk.TicketEvent) bool {
+	if r.Event != "" && r.Event != e.Type {
+		return false
+	}
+	if r.Priority != "" && helpdesk.PriorityLevel(r.Priority) != helpdesk.PriorityLevel(e.Ticket.Priority) {
+		return false
+	}
+	if r.Status != "" && r.Status != e.Ticket.Status {
+		return false
+	}
+	if r.Category != "" && r.Category != e.Ticket.Category {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/jsonl"
+)
+
+// Delivery records an attempt to deliver a webhook.
+type Delivery struct {
+	ID         string    `json:"id"`
+	Endpoint   string    `json:"endpoint"`
+	Event      string    `json:"event"`
+	TicketID   string    `json:"ticketId"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	Duration   string    `json:"duration"`
+}
+
+// DeliveryLog appends deliveries to a JSON lines file.
+// It is safe for concurrent use.
+type DeliveryLog struct {
+	file *jsonl.Appender
+}
+
+func NewDeliveryLog(path string) *DeliveryLog {
+	return &DeliveryLog{file: jsonl.NewAppender(path)}
+}
+
+// Append records d. It does nothing if the log has no file.
+func (l *DeliveryLog) Append(d *Delivery) error {
+	if l == nil {
+		return nil
+	}
+	if err := l.file.Append(d); err != nil {
+		return fmt.Errorf("failed to append delivery log: %w", err)
+	}
+	return nil
+}
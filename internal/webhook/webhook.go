@@ -0,0 +1,199 @@
+// Package webhook notifies HTTP endpoints of ticket events.
+//
+// Each delivery is a POST of the JSON encoded helpdesk.TicketEvent with the
+// headers:
+//
+//	X-Helpdesk-Event:     the event type, e.g. ticket.created
+//	X-Helpdesk-Delivery:  a unique id of the delivery, kept across retries
+//	X-Helpdesk-Timestamp: the Unix time the payload was signed at
+//	X-Helpdesk-Signature: sha256=HEX(HMAC-SHA256(secret, timestamp + "." + body))
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+	"go.uber.org/zap"
+)
+
+// Dispatcher delivers the events of a helpdesk.Watcher to the endpoints
+// whose rules match them.
+type Dispatcher struct {
+	cfg    *Config
+	client *http.Client
+	log    *DeliveryLog
+	zlog   *zap.Logger
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(cfg *Config, zlog *zap.Logger) (*Dispatcher, error) {
+	if cfg == nil {
+		return nil, errors.New("cfg is nil")
+	}
+	if zlog == nil {
+		return nil, errors.New("zlog is nil")
+	}
+
+	return &Dispatcher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout)},
+		log:    NewDeliveryLog(cfg.DeliveryLog),
+		zlog:   zlog.With(zap.String("component", "webhook")),
+	}, nil
+}
+
+// Run delivers events until ctx is done or events is closed, then waits
+// for running deliveries to give up.
+func (d *Dispatcher) Run(ctx context.Context, events <-chan *helpdesk.TicketEvent) {
+	defer d.wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			d.dispatch(ctx, e)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, e *helpdesk.TicketEvent) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.zlog.Error("failed to marshal ticket event", zap.Error(err))
+		return
+	}
+
+	for i := range d.cfg.Endpoints {
+		ep := &d.cfg.Endpoints[i]
+		for j := range ep.Rules {
+			if ep.Rules[j].Match(e) {
+				d.wg.Add(1)
+				go func() {
+					defer d.wg.Done()
+					d.deliver(ctx, ep, e, body)
+				}()
+				break
+			}
+		}
+	}
+}
+
+// deliver posts body to the endpoint, retrying with backoff on network
+// errors, 429 and 5xx responses.
+func (d *Dispatcher) deliver(ctx context.Context, ep *Endpoint, e *helpdesk.TicketEvent, body []byte) {
+	id := newDeliveryID()
+	zlog := d.zlog.With(
+		zap.String("endpoint", ep.Name),
+		zap.String("deliveryId", id),
+		zap.String("event", e.Type),
+		zap.String("ticketId", e.Ticket.ID),
+	)
+
+	backoff := time.Duration(d.cfg.Retry.Backoff)
+	for attempt := 1; attempt <= d.cfg.Retry.Attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, time.Duration(d.cfg.Retry.MaxBackoff))
+		}
+
+		entry := &Delivery{
+			ID:        id,
+			Endpoint:  ep.Name,
+			Event:     e.Type,
+			TicketID:  e.Ticket.ID,
+			Attempt:   attempt,
+			StartedAt: time.Now(),
+		}
+		code, err := d.post(ctx, ep, e.Type, id, body)
+		entry.StatusCode = code
+		entry.Duration = time.Since(entry.StartedAt).String()
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if lerr := d.log.Append(entry); lerr != nil {
+			zlog.Error("failed to record webhook delivery", zap.Error(lerr))
+		}
+
+		if err == nil {
+			zlog.Info("webhook delivered", zap.Int("attempt", attempt), zap.Int("statusCode", code))
+			return
+		}
+		if !retryable(code) {
+			zlog.Error("webhook rejected", zap.Int("attempt", attempt), zap.Int("statusCode", code), zap.Error(err))
+			return
+		}
+		zlog.Warn("failed to deliver webhook", zap.Int("attempt", attempt), zap.Error(err))
+	}
+
+	zlog.Error("gave up delivering webhook", zap.Int("attempts", d.cfg.Retry.Attempts))
+}
+
+func (d *Dispatcher) post(ctx context.Context, ep *Endpoint, event, id string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "helpdesk-dashboard-webhook/1")
+	req.Header.Set("X-Helpdesk-Event", event)
+	req.Header.Set("X-Helpdesk-Delivery", id)
+	req.Header.Set("X-Helpdesk-Timestamp", ts)
+	req.Header.Set("X-Helpdesk-Signature", "sha256="+Sign(ep.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of timestamp + "." + body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable reports whether a delivery that got the status code, zero for
+// no response, is worth retrying.
+func retryable(code int) bool {
+	return code == 0 || code == http.StatusTooManyRequests || code >= 500
+}
+
+func newDeliveryID() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/10664kls/helpdesk-dashboad-api/internal/helpdesk"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "event",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			body:      `{"type":"ticket.created"}`,
+			want:      "3626b2d6b4e109023d27d48b0bd18b6d46501c1e5b6be00e79e770b75873af63",
+		},
+		{
+			name:      "empty body",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			want:      "5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc",
+		},
+		{
+			name:      "empty secret",
+			timestamp: "0",
+			body:      "x",
+			want:      "700eecec9dab1af0c68c5faed9ec417f29e96e084d7a08de14d545c02b0cbc55",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// The timestamp is signed, so a replayed body with a new timestamp
+	// does not verify.
+	if Sign("s", "1", []byte("body")) == Sign("s", "2", []byte("body")) {
+		t.Error("Sign() does not depend on the timestamp")
+	}
+	if Sign("s", "1", []byte("2.body")) == Sign("s", "12", []byte(".body")) {
+		t.Error("Sign() does not separate the timestamp from the body")
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	event := &helpdesk.TicketEvent{
+		Type: helpdesk.EventTicketUpdated,
+		Ticket: &helpdesk.Ticket{
+			Priority: "HIGHT",
+			Status:   "REJECTED",
+			Category: "Hardware",
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{name: "empty rule", rule: Rule{}, want: true},
+		{name: "event", rule: Rule{Event: helpdesk.EventTicketUpdated}, want: true},
+		{name: "other event", rule: Rule{Event: helpdesk.EventTicketCreated}, want: false},
+		{name: "misspelled priority", rule: Rule{Priority: "HIGH"}, want: true},
+		{name: "lower case priority", rule: Rule{Priority: "high"}, want: true},
+		{name: "other priority", rule: Rule{Priority: "LOW"}, want: false},
+		{name: "status", rule: Rule{Status: "REJECTED"}, want: true},
+		{name: "other status", rule: Rule{Status: "RESOLVED"}, want: false},
+		{name: "category", rule: Rule{Category: "Hardware"}, want: true},
+		{name: "other category", rule: Rule{Category: "Software"}, want: false},
+		{
+			name: "all fields",
+			rule: Rule{Event: helpdesk.EventTicketUpdated, Priority: "HIGH", Status: "REJECTED", Category: "Hardware"},
+			want: true,
+		},
+		{
+			name: "one field differs",
+			rule: Rule{Event: helpdesk.EventTicketUpdated, Priority: "HIGH", Status: "REJECTED", Category: "Software"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Match(event); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}